@@ -0,0 +1,338 @@
+package hostinfo
+
+import (
+	"net/netip"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AnsibleFacts returns r rendered as Ansible facts, using the names
+// and shapes of the facts Ansible's setup module gathers, such as
+// "ansible_memtotal_mb" and "ansible_distribution".  Only facts that
+// can be derived from r are included.  The result is suitable for
+// serializing as JSON, for example by a custom facts script in
+// "/etc/ansible/facts.d".
+func (r *HostInfo) AnsibleFacts() map[string]any {
+	t := r.Typed()
+	facts := make(map[string]any)
+
+	if r.MachineID != "" {
+		facts["ansible_machine_id"] = r.MachineID
+	}
+
+	if len(r.OS) > 0 {
+		addAnsibleDistribution(facts, r.OS)
+	}
+
+	if len(t.CPUs) > 0 {
+		addAnsibleProcessor(facts, r, t.CPUs)
+	}
+
+	if len(r.Memory) > 0 {
+		const mb = 20
+		facts["ansible_memtotal_mb"] = t.Memory.TotalBytes >> mb
+		facts["ansible_memfree_mb"] = t.Memory.FreeBytes >> mb
+		facts["ansible_swaptotal_mb"] = t.Memory.SwapTotalBytes >> mb
+		facts["ansible_swapfree_mb"] = t.Memory.SwapFreeBytes >> mb
+	}
+
+	if len(t.Interfaces) > 0 {
+		var names []string
+		for _, iface := range t.Interfaces {
+			names = append(names, iface.Name)
+			facts[ansibleInterfaceKey(iface.Name)] =
+				ansibleInterface(iface, r.Interfaces[iface.Name])
+		}
+		slices.Sort(names)
+		facts["ansible_interfaces"] = names
+	}
+
+	if devices := ansibleDevices(r, t.BlockDevices); len(devices) > 0 {
+		facts["ansible_devices"] = devices
+	}
+
+	return facts
+}
+
+// ansibleDistributions maps os-release IDs to the distribution names
+// Ansible uses, where these differ from os-release's NAME.
+var ansibleDistributions = map[string]string{
+	"almalinux": "AlmaLinux",
+	"alpine":    "Alpine",
+	"arch":      "Archlinux",
+	"centos":    "CentOS",
+	"debian":    "Debian",
+	"fedora":    "Fedora",
+	"rhel":      "RedHat",
+	"rocky":     "Rocky",
+	"ubuntu":    "Ubuntu",
+}
+
+// ansibleOSFamilies maps os-release IDs to Ansible OS families.  IDs
+// which are not listed are looked up using os-release's ID_LIKE.
+var ansibleOSFamilies = map[string]string{
+	"almalinux": "RedHat",
+	"alpine":    "Alpine",
+	"arch":      "Archlinux",
+	"centos":    "RedHat",
+	"debian":    "Debian",
+	"fedora":    "RedHat",
+	"rhel":      "RedHat",
+	"rocky":     "RedHat",
+	"suse":      "Suse",
+	"ubuntu":    "Debian",
+}
+
+func addAnsibleDistribution(facts map[string]any, release map[string]string) {
+	id := release["id"]
+	name, found := ansibleDistributions[id]
+	if !found {
+		name = release["name"]
+	}
+	facts["ansible_distribution"] = name
+
+	version := release["version_id"]
+	major, _, _ := strings.Cut(version, ".")
+	facts["ansible_distribution_version"] = version
+	facts["ansible_distribution_major_version"] = major
+	facts["ansible_distribution_release"] = release["version_codename"]
+
+	family, found := ansibleOSFamilies[id]
+	for _, like := range strings.Fields(release["id_like"]) {
+		if found {
+			break
+		}
+		family, found = ansibleOSFamilies[like]
+	}
+	if !found {
+		family = name
+	}
+	facts["ansible_os_family"] = family
+}
+
+func addAnsibleProcessor(facts map[string]any, r *HostInfo, cpus []CPU) {
+	var processor []string
+	sockets := make(map[int]bool)
+	for _, cpu := range cpus {
+		processor = append(processor,
+			strconv.Itoa(cpu.Index),
+			cpu.VendorID,
+			cpu.ModelName)
+		sockets[cpu.PhysicalID] = true
+	}
+	facts["ansible_processor"] = processor
+	facts["ansible_processor_count"] = len(sockets)
+	facts["ansible_processor_vcpus"] = len(cpus)
+
+	cores := asInt(r.CPUInfo["cpu_cores"])
+	siblings := asInt(r.CPUInfo["siblings"])
+	if cores > 0 {
+		facts["ansible_processor_cores"] = cores
+		if siblings > 0 {
+			facts["ansible_processor_threads_per_core"] = siblings / cores
+		}
+	}
+}
+
+// ansibleInterfaceKey returns the name of the fact describing the
+// named interface.  Like Ansible, dashes and colons in interface
+// names are replaced with underscores.
+func ansibleInterfaceKey(name string) string {
+	return "ansible_" + strings.NewReplacer("-", "_", ":", "_").Replace(name)
+}
+
+func ansibleInterface(iface NetInterface, m map[string]any) map[string]any {
+	result := map[string]any{
+		"device":     iface.Name,
+		"macaddress": iface.MAC,
+		"type":       iface.LinkType,
+		"active":     slices.Contains(iface.Flags, "UP"),
+	}
+	if iface.MTU > 0 {
+		result["mtu"] = iface.MTU
+	}
+	if result["type"] == "" {
+		result["type"] = "unknown"
+	}
+
+	// Scopes aren't in the typed view.
+	scopes := make(map[netip.Addr]string)
+	for _, addr := range asMaps(m["addr_info"]) {
+		if ip, err := netip.ParseAddr(asString(addr["local"])); err == nil {
+			scopes[ip] = asString(addr["scope"])
+		}
+	}
+
+	var ipv4, ipv6 []map[string]any
+	for _, p := range iface.Addresses {
+		ip := p.Addr()
+		prefix := strconv.Itoa(p.Bits())
+		if ip.Is4() {
+			ipv4 = append(ipv4, map[string]any{
+				"address": ip.String(),
+				"netmask": ipv4Netmask(p.Bits()),
+				"network": p.Masked().Addr().String(),
+				"prefix":  prefix,
+			})
+		} else {
+			ipv6 = append(ipv6, map[string]any{
+				"address": ip.String(),
+				"prefix":  prefix,
+				"scope":   scopes[ip],
+			})
+		}
+	}
+	if len(ipv4) > 0 {
+		result["ipv4"] = ipv4[0]
+		if len(ipv4) > 1 {
+			result["ipv4_secondaries"] = ipv4[1:]
+		}
+	}
+	if len(ipv6) > 0 {
+		result["ipv6"] = ipv6
+	}
+
+	return result
+}
+
+// ipv4Netmask returns the dotted-quad netmask for an IPv4 prefix
+// length.
+func ipv4Netmask(bits int) string {
+	mask := ^uint32(0) << (32 - bits)
+	return netip.AddrFrom4([4]byte{
+		byte(mask >> 24),
+		byte(mask >> 16),
+		byte(mask >> 8),
+		byte(mask),
+	}).String()
+}
+
+// ansibleDevices returns the "ansible_devices" fact, which maps the
+// kernel names of block devices other than partitions to their
+// attributes, with each device's partitions nested within it.  Only
+// devices gathered by the "DiskTopology" gatherer are included, as
+// the kernel names of other devices are not known.
+func ansibleDevices(r *HostInfo, bds []BlockDevice) map[string]any {
+	byPath := make(map[string]BlockDevice, len(bds))
+	for _, bd := range bds {
+		byPath[bd.Device] = bd
+	}
+	kname := func(device string) string {
+		return asString(r.Disks[device]["kname"])
+	}
+
+	devices := make(map[string]any)
+	for _, bd := range bds {
+		name := kname(bd.Device)
+		if name == "" || bd.DeviceType == "part" {
+			continue
+		}
+
+		m := r.Disks[bd.Device]
+		device := ansibleDevice(bd, byPath, kname)
+		device["model"] = ansibleNullable(m["model"])
+		device["vendor"] = ansibleNullable(m["vendor"])
+		if serial := asString(m["serial"]); serial != "" {
+			device["serial"] = serial
+		}
+		for _, key := range []string{"removable", "rotational"} {
+			if b, ok := m[key].(bool); ok {
+				device[key] = ansibleFlag(b)
+			}
+		}
+
+		partitions := make(map[string]any)
+		for _, child := range bd.Children {
+			part, found := byPath[child]
+			if !found || part.DeviceType != "part" || kname(child) == "" {
+				continue
+			}
+			p := ansibleDevice(part, byPath, kname)
+			p["uuid"] = ansibleNullable(part.UUID)
+			partitions[kname(child)] = p
+		}
+		device["partitions"] = partitions
+
+		devices[name] = device
+	}
+
+	return devices
+}
+
+// ansibleDevice returns the facts common to block devices and their
+// partitions.  A device's holders are the devices built on it, other
+// than its partitions, which Ansible lists by device-mapper name in
+// "holders" and by kernel name in "links.masters".
+func ansibleDevice(
+	bd BlockDevice,
+	byPath map[string]BlockDevice,
+	kname func(string) string,
+) map[string]any {
+	holders := []string{}
+	links := map[string][]string{
+		"ids":     {},
+		"labels":  {},
+		"masters": {},
+		"uuids":   {},
+	}
+	if bd.UUID != "" {
+		links["uuids"] = append(links["uuids"], bd.UUID)
+	}
+	if bd.Label != "" {
+		links["labels"] = append(links["labels"], bd.Label)
+	}
+	for _, child := range bd.Children {
+		if byPath[child].DeviceType == "part" {
+			continue
+		}
+		holders = append(holders, path.Base(child))
+		if name := kname(child); name != "" {
+			links["masters"] = append(links["masters"], name)
+		}
+	}
+
+	return map[string]any{
+		"holders": holders,
+		"links":   links,
+		"sectors": strconv.FormatUint(bd.SizeBytes/512, 10),
+		"size":    ansibleSize(bd.SizeBytes),
+	}
+}
+
+// ansibleSizeUnits are the units Ansible reports sizes in.
+var ansibleSizeUnits = []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+
+// ansibleSize formats a size in bytes as Ansible does, for example
+// "476.94 GB".
+func ansibleSize(n uint64) string {
+	unit := "Bytes"
+	size := float64(n)
+	for i, u := range ansibleSizeUnits {
+		limit := uint64(1) << (10 * (i + 1))
+		if n < limit {
+			break
+		}
+		unit = u
+		size = float64(n) / float64(limit)
+	}
+	return strconv.FormatFloat(size, 'f', 2, 64) + " " + unit
+}
+
+// ansibleFlag returns b as Ansible reports sysfs flags.
+func ansibleFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// ansibleNullable returns v if it is a non-empty string, and nil,
+// which Ansible reports as null, otherwise.
+func ansibleNullable(v any) any {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package hostinfo
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestAnsibleFacts(t *testing.T) {
+	r := testHostInfo(t)
+	r.MachineID = "0123456789abcdef0123456789abcdef"
+	facts := r.AnsibleFacts()
+
+	assert.Equal(t, facts["ansible_machine_id"], r.MachineID)
+
+	assert.Equal(t, facts["ansible_distribution"], "Ubuntu")
+	assert.Equal(t, facts["ansible_distribution_version"], "22.04")
+	assert.Equal(t, facts["ansible_distribution_major_version"], "22")
+	assert.Equal(t, facts["ansible_distribution_release"], "jammy")
+	assert.Equal(t, facts["ansible_os_family"], "Debian")
+
+	processor := facts["ansible_processor"].([]string)
+	assert.Equal(t, len(processor), 36)
+	assert.DeepEqual(t, processor[3:6], []string{
+		"1",
+		"GenuineIntel",
+		"12th Gen Intel(R) Core(TM) i7-1255U",
+	})
+	assert.Equal(t, facts["ansible_processor_count"], 1)
+	assert.Equal(t, facts["ansible_processor_cores"], 10)
+	assert.Equal(t, facts["ansible_processor_vcpus"], 12)
+
+	assert.Equal(t, facts["ansible_memtotal_mb"], uint64(39788))
+	assert.Equal(t, facts["ansible_swaptotal_mb"], uint64(1955))
+
+	assert.DeepEqual(t, facts["ansible_interfaces"], []string{
+		"br-121144d4a5cf",
+		"br-adeb0277c970",
+		"docker0",
+		"eth0",
+		"lo",
+	})
+	assert.DeepEqual(t, facts["ansible_eth0"], map[string]any{
+		"device":     "eth0",
+		"macaddress": "00:16:3e:ba:ab:67",
+		"mtu":        1500,
+		"type":       "ether",
+		"active":     true,
+		"ipv4": map[string]any{
+			"address": "100.115.92.201",
+			"netmask": "255.255.255.240",
+			"network": "100.115.92.192",
+			"prefix":  "28",
+		},
+		"ipv6": []map[string]any{{
+			"address": "fe80::216:3eff:feba:ab67",
+			"prefix":  "64",
+			"scope":   "link",
+		}},
+	})
+	_, found := facts["ansible_br_adeb0277c970"]
+	assert.Check(t, found)
+
+	// Without topology, kernel names are unknown.
+	_, found = facts["ansible_devices"]
+	assert.Check(t, !found)
+}
+
+func TestAnsibleFacts_devices(t *testing.T) {
+	r := testHostInfo(t)
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns(testLsblkOutput, nil)
+	topology := assertMock(t, gatherDiskTopology, mock)
+	r.merge(&topology)
+
+	devices := r.AnsibleFacts()["ansible_devices"].(map[string]any)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(devices)), []string{
+		"dm-0",
+		"dm-1",
+		"dm-2",
+		"nvme0n1",
+		"sda",
+	})
+
+	nvme := devices["nvme0n1"].(map[string]any)
+	partitions := nvme["partitions"].(map[string]any)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(partitions)), []string{
+		"nvme0n1p1",
+		"nvme0n1p2",
+		"nvme0n1p3",
+	})
+	delete(nvme, "partitions")
+	assert.DeepEqual(t, nvme, map[string]any{
+		"holders": []string{},
+		"links": map[string][]string{
+			"ids":     {},
+			"labels":  {},
+			"masters": {},
+			"uuids":   {},
+		},
+		"model":      "SAMSUNG MZVL2512HCJQ-00BL7",
+		"vendor":     nil,
+		"serial":     "S64KNX0T123456",
+		"removable":  "0",
+		"rotational": "0",
+		"sectors":    "1000215216",
+		"size":       "476.94 GB",
+	})
+
+	assert.DeepEqual(t, partitions["nvme0n1p1"], map[string]any{
+		"holders": []string{},
+		"links": map[string][]string{
+			"ids":     {},
+			"labels":  {},
+			"masters": {},
+			"uuids":   {"8B92-BD41"},
+		},
+		"sectors": "1048576",
+		"size":    "512.00 MB",
+		"uuid":    "8B92-BD41",
+	})
+
+	luks := partitions["nvme0n1p3"].(map[string]any)
+	assert.DeepEqual(t, luks["holders"], []string{"nvme0n1p3_crypt"})
+	assert.DeepEqual(t, luks["links"].(map[string][]string)["masters"],
+		[]string{"dm-0"})
+
+	crypt := devices["dm-0"].(map[string]any)
+	assert.DeepEqual(t, crypt["holders"],
+		[]string{"vgubuntu-root", "vgubuntu-swap_1"})
+	assert.DeepEqual(t, crypt["partitions"], map[string]any{})
+	assert.Equal(t, crypt["model"], nil)
+
+	sda := devices["sda"].(map[string]any)
+	assert.Equal(t, sda["vendor"], "SanDisk")
+	assert.Equal(t, sda["removable"], "1")
+}
+
+func TestAnsibleSize(t *testing.T) {
+	assert.Equal(t, ansibleSize(0), "0.00 Bytes")
+	assert.Equal(t, ansibleSize(1023), "1023.00 Bytes")
+	assert.Equal(t, ansibleSize(1024), "1.00 KB")
+	assert.Equal(t, ansibleSize(30752636928), "28.64 GB")
+}
+
+func TestAnsibleFacts_osFamily(t *testing.T) {
+	for _, tc := range []struct {
+		release map[string]string
+		name    string
+		family  string
+	}{
+		{map[string]string{"id": "fedora", "name": "Fedora Linux"}, "Fedora", "RedHat"},
+		{map[string]string{"id": "pop", "name": "Pop!_OS", "id_like": "ubuntu debian"}, "Pop!_OS", "Debian"},
+		{map[string]string{"id": "weird", "name": "Weird"}, "Weird", "Weird"},
+	} {
+		facts := (&HostInfo{OS: tc.release}).AnsibleFacts()
+		assert.Equal(t, facts["ansible_distribution"], tc.name)
+		assert.Equal(t, facts["ansible_os_family"], tc.family)
+	}
+}
+
+func TestAnsibleFacts_empty(t *testing.T) {
+	assert.Equal(t, len((&HostInfo{}).AnsibleFacts()), 0)
+}
+
+func TestIPv4Netmask(t *testing.T) {
+	assert.Equal(t, ipv4Netmask(0), "0.0.0.0")
+	assert.Equal(t, ipv4Netmask(8), "255.0.0.0")
+	assert.Equal(t, ipv4Netmask(28), "255.255.255.240")
+	assert.Equal(t, ipv4Netmask(32), "255.255.255.255")
+}
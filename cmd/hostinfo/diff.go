@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"gbenson.net/go/hostinfo"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "diff",
+		args:    "[FLAGS] OLD.json NEW.json",
+		summary: "Compare two sets of gathered information",
+		run:     runDiff,
+	})
+}
+
+func runDiff(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	all := fs.Bool("all", false,
+		"include values that change during normal operation")
+	asJSON := fs.Bool("json", false, "output changes as JSON")
+
+	if _, err := parseFlags(ctx, fs, args); err != nil {
+		return err
+	} else if fs.NArg() != 2 {
+		return errUsage
+	}
+
+	var infos [2]*hostinfo.HostInfo
+	for i, filename := range fs.Args() {
+		r, err := readHostInfo(filename)
+		if err != nil {
+			return err
+		}
+		infos[i] = r
+	}
+
+	var opts []hostinfo.DiffOption
+	if *all {
+		opts = append(opts, hostinfo.IncludeVolatile())
+	}
+
+	changes, err := hostinfo.Diff(infos[0], infos[1], opts...)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if changes == nil {
+			changes = []hostinfo.Change{}
+		}
+		if err := writeIndentedJSON(os.Stdout, changes); err != nil {
+			return err
+		}
+	} else {
+		for _, change := range changes {
+			fmt.Println(change.String())
+		}
+	}
+
+	if len(changes) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// readHostInfo reads a HostInfo from the named JSON file.
+func readHostInfo(filename string) (*hostinfo.HostInfo, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var r hostinfo.HostInfo
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"gbenson.net/go/hostinfo"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "fleet",
+		args:    "[FLAGS] INVENTORY",
+		summary: "Gather every host listed in an inventory file",
+		run:     runFleet,
+	})
+}
+
+func runFleet(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var gf gatherFlags
+	gf.register(fs)
+
+	var ssh hostinfo.SSHInvoker
+	fs.StringVar(&ssh.User, "l", "", "log in as `user` on remote hosts")
+	fs.StringVar(&ssh.IdentityFile, "i", "",
+		"authenticate with the private key in `file`")
+	fs.StringVar(&ssh.KnownHostsFile, "known-hosts", "",
+		"check host keys against `file`")
+	fs.StringVar(&ssh.StrictHostKeyChecking, "strict-host-key-checking", "",
+		"ssh StrictHostKeyChecking `setting`")
+	fs.BoolVar(&ssh.ForwardAgent, "A", false,
+		"enable forwarding of the local ssh-agent")
+
+	hostConcurrency := fs.Int("j", hostinfo.DefaultHostConcurrency,
+		"maximum number of hosts to gather in parallel")
+	hostTimeout := fs.Duration("host-timeout", hostinfo.DefaultHostTimeout,
+		"maximum time to spend gathering each host")
+	output := fs.String("o", "", "write results to `file` (default stdout)")
+
+	ctx, err := parseFlags(ctx, fs, args)
+	if err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	hosts, err := hostinfo.LoadInventory(fs.Arg(0), &ssh)
+	if err != nil {
+		return err
+	}
+
+	opts := append(gf.options(),
+		hostinfo.HostConcurrency(*hostConcurrency),
+		hostinfo.HostTimeout(*hostTimeout))
+	results := hostinfo.GatherMany(ctx, hosts, opts...)
+
+	err = writeOutput(*output, func(w io.Writer) error {
+		return writeIndentedJSON(w, results)
+	})
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d hosts failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"gbenson.net/go/hostinfo"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "gather",
+		args:    "[FLAGS]",
+		summary: "Gather information about this host",
+		run:     runGather,
+	})
+}
+
+// formats are the output formats supported by "hostinfo gather".
+var formats = map[string]func(w io.Writer, r *hostinfo.HostInfo) error{
+	"json": func(w io.Writer, r *hostinfo.HostInfo) error {
+		return writeIndentedJSON(w, r)
+	},
+	"compact": func(w io.Writer, r *hostinfo.HostInfo) error {
+		return json.NewEncoder(w).Encode(r)
+	},
+	"ansible": func(w io.Writer, r *hostinfo.HostInfo) error {
+		return writeIndentedJSON(w, r.AnsibleFacts())
+	},
+	"prometheus": hostinfo.WriteMetrics,
+}
+
+func runGather(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var hf hostFlags
+	hf.register(fs)
+
+	names := strings.Join(slices.Sorted(maps.Keys(formats)), ", ")
+	format := fs.String("format", "json", "output `format` ("+names+")")
+	output := fs.String("o", "", "write output to `file` (default stdout)")
+
+	ctx, err := parseFlags(ctx, fs, args)
+	if err != nil {
+		return err
+	} else if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	write, found := formats[*format]
+	if !found {
+		return fmt.Errorf("%q: unknown format", *format)
+	}
+
+	r, err := hf.gather(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The textfile collector must never see a partial file.
+	if *format == "prometheus" && *output != "" && *output != "-" {
+		return hostinfo.WriteMetricsFile(*output, r)
+	}
+
+	return writeOutput(*output, func(w io.Writer) error {
+		return write(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"gbenson.net/go/hostinfo"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "get",
+		args:    "[FLAGS] PATH",
+		summary: "Print a single value, for example operating_system.version_id",
+		run:     runGet,
+	})
+}
+
+func runGet(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var hf hostFlags
+	hf.register(fs)
+
+	input := fs.String("f", "",
+		"read gathered information from `file` instead of gathering")
+
+	ctx, err := parseFlags(ctx, fs, args)
+	if err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return errUsage
+	}
+
+	var doc any
+	if *input != "" {
+		doc, err = readDocument(*input)
+	} else {
+		var r *hostinfo.HostInfo
+		if r, err = hf.gather(ctx); err == nil {
+			doc, err = toDocument(r)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	v, err := lookup(doc, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(formatValue(v))
+	return nil
+}
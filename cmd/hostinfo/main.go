@@ -0,0 +1,278 @@
+// Command hostinfo gathers information about hosts.
+//
+// Usage:
+//
+//	hostinfo COMMAND [FLAGS] [ARGS]
+//
+// Run "hostinfo help" for the list of commands, and "hostinfo
+// COMMAND -h" for help with a specific command.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gbenson.net/go/hostinfo"
+	"gbenson.net/go/invoker"
+	"gbenson.net/go/logger"
+)
+
+// A command is a hostinfo subcommand.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, fs *flag.FlagSet, args []string) error
+}
+
+var commands []*command
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+// run runs the command specified by args, and returns the status
+// with which to exit.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	if len(args) < 1 {
+		usage(stderr)
+		return 2
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(stderr)
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		fs.Bool("v", false, "log debugging messages")
+		fs.Bool("vv", false, "log tracing messages")
+		fs.Usage = func() {
+			fmt.Fprintf(stderr, "usage: hostinfo %s %s\n\n%s.\n",
+				cmd.name, cmd.args, cmd.summary)
+			if hasFlags(fs) {
+				fmt.Fprintln(stderr, "\nFlags:")
+				fs.PrintDefaults()
+			}
+		}
+
+		err := cmd.run(ctx, fs, args[1:])
+		var status exitStatus
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		} else if errors.As(err, &status) {
+			return int(status)
+		} else if errors.Is(err, errUsage) {
+			fs.Usage()
+			return 2
+		} else if err != nil {
+			fmt.Fprintf(stderr, "hostinfo %s: %v\n", cmd.name, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "hostinfo %s: unknown command\n", name)
+	fmt.Fprintln(stderr, "Run 'hostinfo help' for usage.")
+	return 2
+}
+
+// errUsage is returned by commands invoked incorrectly.
+var errUsage = errors.New("usage")
+
+// An exitStatus is returned by commands that should exit with the
+// given status without printing an error.
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// Log levels, as passed to [logger.Logger.Level].
+const (
+	traceLevel = -1
+	debugLevel = 0
+	infoLevel  = 1
+)
+
+// parseFlags parses args with fs, and returns ctx with a logger
+// configured according to the "-v" and "-vv" flags.  The logger is
+// ctx's logger, or the default if it has none, so the command logs
+// the same way as the package.
+func parseFlags(
+	ctx context.Context,
+	fs *flag.FlagSet,
+	args []string,
+) (context.Context, error) {
+	if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil, err
+	} else if err != nil {
+		return nil, exitStatus(2) // fs has reported the error
+	}
+
+	log := logger.Ctx(ctx).Level(infoLevel)
+	if isSet(fs, "vv") {
+		log = log.Level(traceLevel)
+	} else if isSet(fs, "v") {
+		log = log.Level(debugLevel)
+	}
+
+	return log.WithContext(ctx), nil
+}
+
+func isSet(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	return f != nil && f.Value.String() == "true"
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, "usage: hostinfo COMMAND [FLAGS] [ARGS]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprint(w, "\nRun 'hostinfo COMMAND -h' for help with a command.\n")
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// gatherFlags are the flags that control gathering.
+type gatherFlags struct {
+	only        string
+	skip        string
+	concurrency int
+	timeout     time.Duration
+	bytes       bool
+}
+
+func (gf *gatherFlags) register(fs *flag.FlagSet) {
+	names := strings.Join(hostinfo.Gatherers(), ",")
+	fs.StringVar(&gf.only, "only", "",
+		"gather only the given comma-separated `sections` ("+names+")")
+	fs.StringVar(&gf.skip, "skip", "",
+		"do not gather the given comma-separated `sections`")
+	fs.IntVar(&gf.concurrency, "c", hostinfo.DefaultConcurrency,
+		"maximum number of sections to gather in parallel")
+	fs.DurationVar(&gf.timeout, "timeout", hostinfo.DefaultTimeout,
+		"maximum time to spend gathering each section")
+	fs.BoolVar(&gf.bytes, "bytes", false,
+		"report all sizes in bytes")
+}
+
+// hostFlags are the flags that control gathering a single host.
+type hostFlags struct {
+	gatherFlags
+	root   string
+	record string
+	replay string
+}
+
+func (hf *hostFlags) register(fs *flag.FlagSet) {
+	hf.gatherFlags.register(fs)
+	fs.StringVar(&hf.root, "root", "",
+		"gather from the directory tree rooted at `dir`")
+	fs.StringVar(&hf.record, "record", "",
+		"record all commands run to fixture `file`")
+	fs.StringVar(&hf.replay, "replay", "",
+		"replay commands from fixture `file` instead of running them")
+}
+
+// gather gathers the local host, or replays a recorded host, as
+// specified by hf.
+func (hf *hostFlags) gather(ctx context.Context) (*hostinfo.HostInfo, error) {
+	var inv invoker.Invoker = invoker.Exec
+	if hf.replay != "" {
+		fixture, err := hostinfo.LoadFixture(hf.replay)
+		if err != nil {
+			return nil, err
+		}
+		inv = hostinfo.NewReplayer(fixture)
+	}
+
+	var recorder *hostinfo.Recorder
+	if hf.record != "" {
+		recorder = hostinfo.NewRecorder(inv)
+		inv = recorder
+	}
+
+	opts := hf.options()
+	if hf.root != "" {
+		root, err := filepath.Abs(hf.root)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, hostinfo.WithRoot(root))
+	}
+
+	r, err := hostinfo.Gather(ctx, inv, opts...)
+	if recorder != nil {
+		if err := recorder.Fixture().Save(hf.record); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, err
+}
+
+func (gf *gatherFlags) options() []hostinfo.Option {
+	opts := []hostinfo.Option{
+		hostinfo.Concurrency(gf.concurrency),
+		hostinfo.Timeout(gf.timeout),
+	}
+	if gf.only != "" {
+		opts = append(opts, hostinfo.Only(strings.Split(gf.only, ",")...))
+	}
+	if gf.skip != "" {
+		opts = append(opts, hostinfo.Skip(strings.Split(gf.skip, ",")...))
+	}
+	if gf.bytes {
+		opts = append(opts, hostinfo.NormalizeUnits())
+	}
+	return opts
+}
+
+// writeOutput calls write with the named file, or with the standard
+// output if filename is empty or "-".
+func writeOutput(filename string, write func(io.Writer) error) error {
+	if filename == "" || filename == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeIndentedJSON writes v to w as indented JSON.
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
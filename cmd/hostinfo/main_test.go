@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gbenson.net/go/hostinfo"
+	"gbenson.net/go/logger"
+	"gotest.tools/v3/assert"
+)
+
+const (
+	testMachineID = "0123456789abcdef0123456789abcdef"
+	testOSRelease = "ID=ubuntu\nVERSION_ID=\"24.04\"\n"
+)
+
+// writeFixture writes a fixture of a host whose files are under root
+// to the named file.
+func writeFixture(t *testing.T, filename, root string) {
+	t.Helper()
+	f := &hostinfo.Fixture{Invocations: []*hostinfo.Invocation{{
+		Command: []string{"cat", root + "/etc/machine-id"},
+		Stdout:  testMachineID + "\n",
+	}, {
+		Command: []string{"cat", root + "/etc/os-release"},
+		Stdout:  testOSRelease,
+	}}}
+	assert.NilError(t, f.Save(filename))
+}
+
+// runCommand runs the command specified by args, and returns its exit
+// status and everything it wrote to the standard output and error.
+func runCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	assert.NilError(t, err)
+	defer f.Close()
+
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+
+	var stderr bytes.Buffer
+	status := run(logger.TestContext(t), args, &stderr)
+
+	_, err = f.Seek(0, io.SeekStart)
+	assert.NilError(t, err)
+	b, err := io.ReadAll(f)
+	assert.NilError(t, err)
+
+	return status, string(b), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	writeFixture(t, "host.json", "")
+	writeFixture(t, "image.json", filepath.Join(dir, "image"))
+
+	old := `{"operating_system": {"id": "ubuntu", "version_id": "22.04"}}`
+	assert.NilError(t, os.WriteFile("old.json", []byte(old), 0o644))
+	new := `{"operating_system": {"id": "ubuntu", "version_id": "24.04"}}`
+	assert.NilError(t, os.WriteFile("new.json", []byte(new), 0o644))
+
+	replay := []string{"-replay", "host.json", "-only", "MachineID,OSRelease"}
+
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		status int
+		stdout string // exact, unless check is set
+		stderr string // substring
+		check  func(t *testing.T, stdout string)
+	}{{
+		name:   "no command",
+		status: 2,
+		stderr: "usage: hostinfo COMMAND",
+	}, {
+		name:   "help",
+		args:   []string{"help"},
+		stderr: "Commands:",
+	}, {
+		name:   "unknown command",
+		args:   []string{"frob"},
+		status: 2,
+		stderr: "hostinfo frob: unknown command",
+	}, {
+		name:   "command help",
+		args:   []string{"gather", "-h"},
+		stderr: "usage: hostinfo gather [FLAGS]",
+	}, {
+		name:   "bad flag",
+		args:   []string{"gather", "-frob"},
+		status: 2,
+		stderr: "flag provided but not defined: -frob\nusage: hostinfo gather",
+	}, {
+		name:   "gather extra argument",
+		args:   []string{"gather", "frob"},
+		status: 2,
+		stderr: "usage: hostinfo gather",
+	}, {
+		name: "gather",
+		args: append([]string{"gather", "-v"}, replay...),
+		check: func(t *testing.T, stdout string) {
+			assert.Check(t, strings.HasPrefix(stdout, "{\n  "))
+			assert.Check(t, strings.Contains(stdout,
+				`"machine_id": "`+testMachineID+`"`))
+		},
+	}, {
+		name: "gather compact",
+		args: append([]string{"gather", "-vv", "-format", "compact"}, replay...),
+		check: func(t *testing.T, stdout string) {
+			assert.Check(t, strings.Contains(stdout,
+				`"operating_system":{"id":"ubuntu","version_id":"24.04"}`))
+		},
+	}, {
+		name:   "gather unknown format",
+		args:   append([]string{"gather", "-format", "yaml"}, replay...),
+		status: 1,
+		stderr: `hostinfo gather: "yaml": unknown format`,
+	}, {
+		name:   "gather unknown section",
+		args:   []string{"gather", "-replay", "host.json", "-only", "Frob"},
+		status: 1,
+		stderr: "Frob",
+	}, {
+		name:   "gather missing fixture",
+		args:   []string{"gather", "-replay", "missing.json"},
+		status: 1,
+		stderr: "no such file or directory",
+	}, {
+		name: "gather relative root",
+		args: []string{"gather", "-replay", "image.json", "-root", "image"},
+		check: func(t *testing.T, stdout string) {
+			assert.Check(t, strings.Contains(stdout, `"version_id": "24.04"`))
+		},
+	}, {
+		name:   "get",
+		args:   append(append([]string{"get"}, replay...), "operating_system.version_id"),
+		stdout: "24.04\n",
+	}, {
+		name:   "get from file",
+		args:   []string{"get", "-f", "old.json", "operating_system.version_id"},
+		stdout: "22.04\n",
+	}, {
+		name:   "get missing path",
+		args:   []string{"get", "-f", "old.json"},
+		status: 2,
+		stderr: "usage: hostinfo get",
+	}, {
+		name:   "diff unchanged",
+		args:   []string{"diff", "old.json", "old.json"},
+		stdout: "",
+	}, {
+		name:   "diff changed",
+		args:   []string{"diff", "old.json", "new.json"},
+		status: 1,
+		stdout: "~ operating_system.version_id: 22.04 -> 24.04\n",
+	}, {
+		name:   "diff unchanged json",
+		args:   []string{"diff", "-json", "old.json", "old.json"},
+		stdout: "[]\n",
+	}, {
+		name:   "diff missing file",
+		args:   []string{"diff", "old.json", "missing.json"},
+		status: 1,
+		stderr: "hostinfo diff: open missing.json",
+	}, {
+		name:   "diff one file",
+		args:   []string{"diff", "old.json"},
+		status: 2,
+		stderr: "usage: hostinfo diff",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, stdout, stderr := runCommand(t, tc.args...)
+			assert.Equal(t, status, tc.status, stderr)
+			if tc.check != nil {
+				tc.check(t, stdout)
+			} else {
+				assert.Equal(t, stdout, tc.stdout)
+			}
+			assert.Check(t, strings.Contains(stderr, tc.stderr), stderr)
+		})
+	}
+}
+
+func TestRun_gatherOutput(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFixture(t, "host.json", "")
+
+	for _, tc := range []struct {
+		format, output, want string
+	}{
+		{"json", "out.json", `"machine_id": "` + testMachineID + `"`},
+		{"ansible", "facts.json", `"ansible_machine_id": "` + testMachineID + `"`},
+		{"prometheus", "hostinfo.prom", `machine_id="` + testMachineID + `"`},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			status, stdout, stderr := runCommand(t, "gather",
+				"-replay", "host.json", "-only", "MachineID,OSRelease",
+				"-format", tc.format, "-o", tc.output)
+			assert.Equal(t, status, 0, stderr)
+			assert.Equal(t, stdout, "")
+
+			b, err := os.ReadFile(filepath.Join(dir, tc.output))
+			assert.NilError(t, err)
+			assert.Check(t, strings.Contains(string(b), tc.want), string(b))
+		})
+	}
+
+	// Nothing else, such as temporary files, was left behind.
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.DeepEqual(t, names, []string{
+		"facts.json", "host.json", "hostinfo.prom", "out.json",
+	})
+}
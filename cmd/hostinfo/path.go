@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gbenson.net/go/hostinfo"
+)
+
+// readDocument reads a JSON document from the named file, or from
+// the standard input if filename is "-".
+func readDocument(filename string) (any, error) {
+	var b []byte
+	var err error
+	if filename == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// toDocument returns r as a generic JSON document.
+func toDocument(r *hostinfo.HostInfo) (any, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// lookup returns the value at path in doc.  Paths are sequences of
+// object keys and array indexes separated by dots, for example
+// "cpus.0.core_id".  Object keys may themselves contain dots, so
+// "network_interfaces.eth0.100.mtu" finds the MTU of "eth0.100".
+func lookup(doc any, path string) (any, error) {
+	if path == "" {
+		return doc, nil
+	}
+
+	switch v := doc.(type) {
+	case map[string]any:
+		// Prefer the longest key that matches.
+		var keys []string
+		for key := range v {
+			if path == key || strings.HasPrefix(path, key+".") {
+				keys = append(keys, key)
+			}
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return len(b) - len(a)
+		})
+		for _, key := range keys {
+			rest := strings.TrimPrefix(strings.TrimPrefix(path, key), ".")
+			if result, err := lookup(v[key], rest); err == nil {
+				return result, nil
+			}
+		}
+
+	case []any:
+		head, rest, _ := strings.Cut(path, ".")
+		if i, err := strconv.Atoi(head); err == nil && i >= 0 && i < len(v) {
+			return lookup(v[i], rest)
+		}
+	}
+
+	return nil, fmt.Errorf("%q: not found", path)
+}
+
+// formatValue formats v for output.  Strings are output as-is, and
+// everything else as JSON.
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+const testDocument = `{
+  "cpus": [{"core_id": 0}, {"core_id": 1}],
+  "network_interfaces": {
+    "eth0": {"mtu": 1500},
+    "eth0.100": {"mtu": 1496, "addr_info": []}
+  },
+  "block_devices": {
+    "/dev/sda1": {"uuid": "8B92-BD41"}
+  },
+  "operating_system": {"version_id": "22.04"}
+}`
+
+func TestLookup(t *testing.T) {
+	var doc any
+	assert.NilError(t, json.Unmarshal([]byte(testDocument), &doc))
+
+	for path, want := range map[string]string{
+		"cpus.1.core_id":                  "1",
+		"network_interfaces.eth0.mtu":     "1500",
+		"network_interfaces.eth0.100.mtu": "1496",
+		"block_devices./dev/sda1.uuid":    "8B92-BD41",
+		"operating_system.version_id":     "22.04",
+		"operating_system":                `{"version_id":"22.04"}`,
+	} {
+		v, err := lookup(doc, path)
+		assert.NilError(t, err, path)
+		assert.Equal(t, formatValue(v), want, path)
+	}
+
+	for _, path := range []string{
+		"cpus.2",
+		"cpus.-1",
+		"cpus.x",
+		"network_interfaces.eth1",
+		"operating_system.version_id.major",
+	} {
+		_, err := lookup(doc, path)
+		assert.Error(t, err, `"`+path+`": not found`)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"time"
+
+	"gbenson.net/go/hostinfo"
+	"gbenson.net/go/invoker"
+	"gbenson.net/go/logger"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "serve",
+		args:    "[FLAGS]",
+		summary: "Serve information about this host over HTTP",
+		run:     runServe,
+	})
+}
+
+func runServe(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var gf gatherFlags
+	gf.register(fs)
+
+	listen := fs.String("listen", "localhost:8080",
+		"listen for HTTP requests on `address`")
+	ttl := fs.Duration("ttl", hostinfo.DefaultCacheTTL,
+		"time to cache each section for")
+
+	ctx, err := parseFlags(ctx, fs, args)
+	if err != nil {
+		return err
+	} else if fs.NArg() != 0 {
+		return errUsage
+	}
+
+	opts := append(gf.options(), hostinfo.CacheTTL(*ttl))
+	h, err := hostinfo.NewHandler(invoker.Exec, opts...)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	logger.Ctx(ctx).Info().
+		Str("address", ln.Addr().String()).
+		Msg("Listening")
+
+	srv := &http.Server{
+		Handler:     h,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -12,7 +12,7 @@ import (
 )
 
 // gatherCPUInfo gathers the content of `/proc/cpuinfo`.
-func gatherCPUInfo(gi *gatherInvoker, r *HostInfo) error {
+func gatherCPUInfo(gi *GatherInvoker, r *HostInfo) error {
 	s, err := gi.ReadFile("/proc/cpuinfo")
 	if err != nil {
 		return err
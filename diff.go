@@ -0,0 +1,288 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// A ChangeKind describes how a value differs between two [HostInfo].
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// A Path locates a value within a [HostInfo].  Its elements are the
+// object keys used in the JSON serialization of the HostInfo, and
+// the indexes of list items as decimal strings, except for lists of
+// interface addresses, whose items are identified by address and
+// prefix length, for example "fe80::216:3eff:feba:ab67/64".
+type Path []string
+
+// String returns the elements of p separated by dots.
+func (p Path) String() string {
+	return strings.Join(p, ".")
+}
+
+// A Change describes a single difference between two [HostInfo].
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Path Path       `json:"path"`
+
+	// Old and New are the values at Path before and after the
+	// change, in the form returned by [json.Unmarshal].  Old is nil
+	// for additions, and New is nil for removals.
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// String returns a one-line description of the change.
+func (c *Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatDiffValue(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, formatDiffValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path,
+			formatDiffValue(c.Old), formatDiffValue(c.New))
+	}
+}
+
+func formatDiffValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// A DiffOption configures [Diff].
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	includeVolatile bool
+}
+
+// IncludeVolatile causes [Diff] to report changes to values that
+// change during normal operation, such as free memory, which are
+// ignored by default.
+func IncludeVolatile() DiffOption {
+	return func(o *diffOptions) {
+		o.includeVolatile = true
+	}
+}
+
+// Diff returns the differences between old and new, ordered by path.
+// Values added or removed in their entirety, such as new disks or
+// interfaces, are reported as single changes rather than as changes
+// to each of their members.  Either of old and new may be nil, which
+// is treated as an empty HostInfo.
+func Diff(old, new *HostInfo, opts ...DiffOption) ([]Change, error) {
+	o := &diffOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ov, err := toGeneric(old)
+	if err != nil {
+		return nil, err
+	}
+	nv, err := toGeneric(new)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{options: o}
+	d.diff(nil, ov, nv)
+	return d.changes, nil
+}
+
+// toGeneric returns r as a generic JSON value.
+func toGeneric(r *HostInfo) (any, error) {
+	if r == nil {
+		return map[string]any{}, nil
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type differ struct {
+	options *diffOptions
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path Path, old, new any) {
+	if !d.options.includeVolatile && isVolatile(path) {
+		return
+	}
+	d.changes = append(d.changes, Change{kind, slices.Clone(path), old, new})
+}
+
+func (d *differ) diff(path Path, old, new any) {
+	if !d.options.includeVolatile && isVolatile(path) {
+		return
+	}
+
+	switch ov := old.(type) {
+	case map[string]any:
+		if nv, ok := new.(map[string]any); ok {
+			d.diffMaps(path, ov, nv)
+			return
+		}
+
+	case []any:
+		nv, ok := new.([]any)
+		if !ok {
+			break
+		}
+		if key := listKeyFunc(path); key != nil {
+			om, ok1 := keyedList(ov, key)
+			nm, ok2 := keyedList(nv, key)
+			if ok1 && ok2 {
+				d.diffMaps(path, om, nm)
+				return
+			}
+		}
+		d.diffLists(path, ov, nv)
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		d.add(Changed, path, old, new)
+	}
+}
+
+func (d *differ) diffMaps(path Path, old, new map[string]any) {
+	keys := slices.Collect(maps.Keys(old))
+	for key := range new {
+		if _, found := old[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		ov, inOld := old[key]
+		nv, inNew := new[key]
+		path := append(path, key)
+		switch {
+		case !inOld:
+			d.add(Added, path, nil, nv)
+		case !inNew:
+			d.add(Removed, path, ov, nil)
+		default:
+			d.diff(path, ov, nv)
+		}
+	}
+}
+
+func (d *differ) diffLists(path Path, old, new []any) {
+	for i := range max(len(old), len(new)) {
+		path := append(path, fmt.Sprint(i))
+		switch {
+		case i >= len(old):
+			d.add(Added, path, nil, new[i])
+		case i >= len(new):
+			d.add(Removed, path, old[i], nil)
+		default:
+			d.diff(path, old[i], new[i])
+		}
+	}
+}
+
+// listKeyFunc returns a function that identifies the items of the
+// list at path, or nil if the list's items are identified by index.
+func listKeyFunc(path Path) func(map[string]any) string {
+	if len(path) == 3 &&
+		path[0] == "network_interfaces" &&
+		path[2] == "addr_info" {
+		return addrInfoKey
+	}
+	return nil
+}
+
+// addrInfoKey identifies an address in an "addr_info" list.
+func addrInfoKey(addr map[string]any) string {
+	local, ok := addr["local"].(string)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s/%v", local, addr["prefixlen"])
+}
+
+// keyedList converts a list into a map using key to identify its
+// items.  It returns false if any item is not an object, cannot be
+// identified, or has the same identity as another item.
+func keyedList(
+	list []any,
+	key func(map[string]any) string,
+) (map[string]any, bool) {
+	result := make(map[string]any, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		k := key(m)
+		if _, found := result[k]; found || k == "" {
+			return nil, false
+		}
+		result[k] = m
+	}
+	return result, true
+}
+
+// isVolatile reports whether the value at path is expected to change
+// during normal operation.
+func isVolatile(path Path) bool {
+	switch {
+	case len(path) > 0 && path[0] == "meta":
+		return true
+
+	case len(path) == 2 && path[0] == "memory":
+		// Everything in /proc/meminfo except the totals and sizes.
+		key := path[1]
+		return !strings.Contains(key, "total") &&
+			!strings.Contains(key, "size")
+
+	case len(path) == 2 && path[0] == "kernel":
+		return path[1] == "uptime_seconds"
+
+	case len(path) == 3 && path[0] == "mounts":
+		// Filesystem usage.
+		return path[2] == "free_bytes" ||
+			path[2] == "available_bytes" ||
+			path[2] == "inodes_free"
+
+	case len(path) == 2 && path[0] == "cpu_info":
+		return path[1] == "cpu_mhz"
+
+	case len(path) == 3 && path[0] == "cpus":
+		return path[2] == "cpu_mhz"
+
+	case len(path) == 5 && path[0] == "network_interfaces":
+		return path[4] == "valid_life_time" ||
+			path[4] == "preferred_life_time"
+	}
+
+	return false
+}
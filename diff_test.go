@@ -0,0 +1,159 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+// testHostInfo returns a HostInfo gathered from test resources.
+func testHostInfo(t *testing.T) *HostInfo {
+	t.Helper()
+
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("cat", "/proc/cpuinfo").
+		Returns(x86CPUInfo, nil)
+	mock.ExpectInvoke("cat", "/proc/meminfo").
+		Returns(testMemInfo, nil)
+	mock.ExpectInvoke("ip", "--json", "address", "show").
+		Returns(testInterfaces, nil)
+	mock.ExpectInvoke("cat", "/etc/os-release").
+		Returns(ubuntuOSRelease, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("DiskAttrs", "CPUInfo", "MemInfo", "Interfaces", "OSRelease"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Check(t, r.Partial == nil)
+	assert.Check(t, r.Meta != nil)
+	r.Meta = nil // timings differ between calls
+
+	// Round-trip, so r looks like it was loaded from storage.
+	b, err := json.Marshal(r)
+	assert.NilError(t, err)
+	var result HostInfo
+	assert.NilError(t, json.Unmarshal(b, &result))
+	return &result
+}
+
+func TestDiff_same(t *testing.T) {
+	old := testHostInfo(t)
+	new := testHostInfo(t)
+
+	changes, err := Diff(old, new)
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 0)
+
+	changes, err = Diff(old, new, IncludeVolatile())
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 0)
+}
+
+func TestDiff(t *testing.T) {
+	old := testHostInfo(t)
+	new := testHostInfo(t)
+
+	// A new interface address.
+	eth0 := new.Interfaces["eth0"]
+	eth0["addr_info"] = append([]any{map[string]any{
+		"family":    "inet",
+		"local":     "192.168.1.7",
+		"prefixlen": float64(24),
+	}}, eth0["addr_info"].([]any)...)
+
+	// A changed disk UUID.
+	new.Disks["/dev/nvme0n1p1"]["uuid"] = "1234-5678"
+
+	// A new disk.
+	new.Disks["/dev/sda1"] = map[string]any{"type": "ext4"}
+
+	// A removed interface.
+	delete(new.Interfaces, "docker0")
+
+	// An upgrade.
+	new.OS["version_id"] = "24.04"
+
+	// Less memory.
+	new.Memory["mem_total_kb"] = float64(32_000_000)
+
+	// Things that change all the time.
+	new.Memory["mem_free_kb"] = float64(12345)
+	new.CPUs[3]["cpu_mhz"] = float64(1234)
+
+	changes, err := Diff(old, new)
+	assert.NilError(t, err)
+
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	assert.DeepEqual(t, got, []string{
+		`~ block_devices./dev/nvme0n1p1.uuid: 8B92-BD41 -> 1234-5678`,
+		`+ block_devices./dev/sda1: {"type":"ext4"}`,
+		`~ memory.mem_total_kb: 40743392 -> 32000000`,
+		`- network_interfaces.docker0: ` + jsonString(t, old.Interfaces["docker0"]),
+		`+ network_interfaces.eth0.addr_info.192.168.1.7/24: ` +
+			`{"family":"inet","local":"192.168.1.7","prefixlen":24}`,
+		`~ operating_system.version_id: 22.04 -> 24.04`,
+	})
+
+	c := changes[4]
+	assert.Equal(t, c.Kind, Added)
+	assert.DeepEqual(t, c.Path, Path{
+		"network_interfaces",
+		"eth0",
+		"addr_info",
+		"192.168.1.7/24",
+	})
+	assert.Check(t, c.Old == nil)
+
+	changes, err = Diff(old, new, IncludeVolatile())
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 8)
+}
+
+func TestDiff_meta(t *testing.T) {
+	old := &HostInfo{Meta: &Meta{Duration: time.Second}}
+	new := &HostInfo{Meta: &Meta{Duration: time.Minute}}
+
+	changes, err := Diff(old, new)
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 0)
+
+	changes, err = Diff(old, new, IncludeVolatile())
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Path.String(), "meta.duration_ns")
+}
+
+func TestDiff_nil(t *testing.T) {
+	r := &HostInfo{MachineID: "0123456789abcdef0123456789abcdef"}
+
+	changes, err := Diff(nil, r)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changes, []Change{{
+		Kind: Added,
+		Path: Path{"machine_id"},
+		New:  "0123456789abcdef0123456789abcdef",
+	}})
+
+	changes, err = Diff(r, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 1)
+	assert.Equal(t, changes[0].Kind, Removed)
+}
+
+func jsonString(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NilError(t, err)
+	return string(b)
+}
@@ -3,8 +3,10 @@ package hostinfo
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
@@ -13,19 +15,23 @@ import (
 	"gbenson.net/go/strcase"
 )
 
-// gatherDiskAttrs gathers the output of `/sbin/blkid`.
-func gatherDiskAttrs(gi *gatherInvoker, r *HostInfo) error {
-	s, err := gi.Invoke("/sbin/blkid")
-	if err != nil {
-		return err
-	}
-
-	scanner := bufio.NewScanner(bufio.NewReader(bytes.NewBufferString(s)))
-	for scanner.Scan() {
-		if err := gatherDiskAttr(gi, r, scanner.Text()); err != nil {
-			return err
+// gatherDiskAttrs gathers the output of `blkid -o export`, or, if
+// that fails, of `/sbin/blkid`.
+func gatherDiskAttrs(gi *GatherInvoker, r *HostInfo) error {
+	disks, err1 := gatherBlkidExport(gi)
+	if err1 != nil {
+		gi.Logger().Debug().
+			AnErr("reason", err1).
+			Msg("Falling back to blkid")
+
+		var err2 error
+		if disks, err2 = gatherBlkidList(gi); err2 != nil {
+			return errors.Join(err1, err2)
 		}
 	}
+
+	r.Disks = disks
+	gatherLUKSInfos(gi, r)
 	return nil
 }
 
@@ -35,7 +41,119 @@ func blkidError(line string) error {
 	return &InvalidLineError{"blkid", line}
 }
 
-func gatherDiskAttr(gi *gatherInvoker, r *HostInfo, line string) error {
+// blkidValue returns the value of the named attribute, which must
+// have been lowercased, with the appropriate type.
+func blkidValue(attr, value string) any {
+	if attr == "block_size" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+// gatherBlkidExport gathers the output of `blkid -o export`.
+func gatherBlkidExport(gi *GatherInvoker) (map[string]map[string]any, error) {
+	s, err := gi.Invoke("/sbin/blkid", "-o", "export")
+	if err != nil {
+		return nil, err
+	}
+	return parseBlkidExport(gi, s)
+}
+
+// parseBlkidExport parses the output of `blkid -o export`, which
+// comprises one KEY=value pair per line, with each device's pairs
+// starting with DEVNAME and separated from the next device's by a
+// blank line.  Values are escaped for the shell with backslashes.
+func parseBlkidExport(gi *GatherInvoker, s string) (map[string]map[string]any, error) {
+	log := gi.Logger()
+	disks := make(map[string]map[string]any)
+
+	var attrs map[string]any
+	for _, line := range strings.Split(s, "\n") {
+		// Don't trim line: trailing spaces are escaped.
+		if strings.TrimSpace(line) == "" {
+			attrs = nil
+			continue
+		}
+
+		attr, value, found := strings.Cut(line, "=")
+		if !found || blkidAttrRx.FindString(attr) != attr {
+			return nil, blkidError(line)
+		}
+
+		if attr == "DEVNAME" {
+			// DEVNAME is the only value that is not escaped.
+			if value == "" {
+				return nil, blkidError(line)
+			}
+			log.Trace().
+				Str("device", value).
+				Msg("Got")
+
+			attrs = make(map[string]any)
+			disks[value] = attrs
+			continue
+		} else if attrs == nil {
+			return nil, blkidError(line) // no DEVNAME
+		}
+
+		value, err := unescapeBlkidExport(value)
+		if err != nil {
+			return nil, blkidError(line)
+		}
+		attr = strings.ToLower(attr)
+
+		log.Trace().
+			Str("attr", attr).
+			Str("value", value).
+			Msg("Got")
+
+		attrs[attr] = blkidValue(attr, value)
+	}
+
+	return disks, nil
+}
+
+// unescapeBlkidExport removes the backslashes `blkid -o export`
+// escapes shell metacharacters in values with.
+func unescapeBlkidExport(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			if i++; i == len(s) {
+				return "", strconv.ErrSyntax
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), nil
+}
+
+// gatherBlkidList gathers the output of `/sbin/blkid` with no options,
+// which lists each device on one line as `DEVICE: KEY="value" ...`.
+func gatherBlkidList(gi *GatherInvoker) (map[string]map[string]any, error) {
+	s, err := gi.Invoke("/sbin/blkid")
+	if err != nil {
+		return nil, err
+	}
+
+	var r HostInfo
+	scanner := bufio.NewScanner(bufio.NewReader(bytes.NewBufferString(s)))
+	for scanner.Scan() {
+		if err := gatherDiskAttr(gi, &r, scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.Disks, nil
+}
+
+func gatherDiskAttr(gi *GatherInvoker, r *HostInfo, line string) error {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil
@@ -97,32 +215,42 @@ func gatherDiskAttr(gi *gatherInvoker, r *HostInfo, line string) error {
 			Str("value", value).
 			Msg("Got")
 
-		untypedValue := any(value)
-		if attr == "block_size" {
-			if n, err := strconv.Atoi(value); err == nil {
-				untypedValue = n
-			}
-		}
-
-		attrs[attr] = untypedValue
+		attrs[attr] = blkidValue(attr, value)
 
 		attr = nextattr
 	}
 
-	if attrs["type"] == "crypto_LUKS" {
-		luks, err := gatherLUKSInfo(gi, device)
-		if err == nil {
-			attrs["luks"] = luks
-		} else {
-			log.Warn().
+	return nil
+}
+
+// gatherLUKSInfos gathers the LUKS headers of every encrypted device
+// in r.Disks, dumping up to gi.concurrency devices in parallel.
+func gatherLUKSInfos(gi *GatherInvoker, r *HostInfo) {
+	var devices []string
+	for device, attrs := range r.Disks {
+		if attrs["type"] == "crypto_LUKS" {
+			devices = append(devices, device)
+		}
+	}
+	slices.Sort(devices)
+
+	results := make([]map[string]any, len(devices))
+	errs := make([]error, len(devices))
+	parallel(len(devices), gi.concurrency, func(i int) {
+		results[i], errs[i] = gatherLUKSInfo(gi, devices[i])
+	})
+
+	for i, device := range devices {
+		if err := errs[i]; err != nil {
+			gi.Logger().Warn().
 				Str("item", "LUKSInfo").
 				Str("device", device).
 				AnErr("reason", err).
 				Msg("Gather failed")
+			continue
 		}
+		r.Disks[device]["luks"] = results[i]
 	}
-
-	return nil
 }
 
 func luksDumpError(line string) error {
@@ -130,7 +258,7 @@ func luksDumpError(line string) error {
 }
 
 // gatherLUKSInfo gathers the output of `cryptsetup luksDump`.
-func gatherLUKSInfo(gi *gatherInvoker, device string) (map[string]any, error) {
+func gatherLUKSInfo(gi *GatherInvoker, device string) (map[string]any, error) {
 	s, err := gi.InvokeRetrySudo("cryptsetup", "luksDump", device)
 	if err != nil {
 		return nil, err
@@ -150,7 +278,7 @@ func gatherLUKSInfo(gi *gatherInvoker, device string) (map[string]any, error) {
 // gatherLUKSInfoMapping gathers a YAML-style mapping from the output
 // of `cryptsetup luksDump`.
 func gatherLUKSInfoMapping(
-	gi *gatherInvoker,
+	gi *GatherInvoker,
 	scanner *bufio.Scanner,
 	indent string,
 ) (result map[string]any, line string, err error) {
@@ -222,7 +350,7 @@ func gatherLUKSInfoMapping(
 // gatherLUKSInfoSlice gathers a YAML-style list from the output of
 // `cryptsetup luksDump`.
 func gatherLUKSInfoSlice(
-	gi *gatherInvoker,
+	gi *GatherInvoker,
 	scanner *bufio.Scanner,
 	indent string,
 ) (result []map[string]any, line string, err error) {
@@ -2,8 +2,10 @@ package hostinfo
 
 import (
 	_ "embed"
+	"errors"
 	"maps"
 	"slices"
+	"strings"
 	"testing"
 
 	"gbenson.net/go/invoker"
@@ -13,13 +15,16 @@ import (
 //go:embed resources/blkid.out
 var blkidOutput []byte
 
+//go:embed resources/blkid-export.out
+var blkidExportOutput []byte
+
 //go:embed resources/luksdump
 var luksdump []byte
 
 func TestGatherDiskAttrs(t *testing.T) {
 	mock := invoker.NewMock(t)
-	mock.ExpectInvoke("/sbin/blkid").
-		Returns(blkidOutput, nil)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(blkidExportOutput, nil)
 	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
 		Returns(luksdump, nil)
 
@@ -96,3 +101,207 @@ func TestGatherDiskAttrs(t *testing.T) {
 		"39 38 41 52 8b ca 8a d4 61 5d 2a 37 b6 01 "+
 		"c4 76 52 ac e4 5c 7d 77 f3 9b 9f 25 2a de")
 }
+
+func TestGatherDiskAttrs_fallback(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(nil, errors.New("exit status 4"))
+	mock.ExpectInvoke("/sbin/blkid").
+		Returns(blkidOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+
+	r := assertMock(t, gatherDiskAttrs, mock)
+
+	gi := &GatherInvoker{context: testctx(t)}
+	want, err := parseBlkidExport(gi, string(blkidExportOutput))
+	assert.NilError(t, err)
+	for device, attrs := range r.Disks {
+		delete(attrs, "luks")
+		assert.DeepEqual(t, attrs, want[device])
+	}
+	assert.Equal(t, len(r.Disks), len(want))
+}
+
+func TestGatherDiskAttrs_failed(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(nil, errors.New("exit status 2"))
+	mock.ExpectInvoke("/sbin/blkid").
+		Returns(nil, errors.New("exit status 2"))
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock}
+	var r HostInfo
+	err := gatherDiskAttrs(gi, &r)
+	assert.ErrorContains(t, err, "exit status 2\nexit status 2")
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Check(t, r.Disks == nil)
+}
+
+func TestParseBlkidExport(t *testing.T) {
+	gi := &GatherInvoker{context: testctx(t)}
+	disks, err := parseBlkidExport(gi, "DEVNAME=/dev/sdb1\n"+
+		`LABEL=a\ \"b\"\ c=d\\\ `+"\n"+
+		"UUID=5d5d\n"+
+		"BLOCK_SIZE=4096\n"+
+		"TYPE=ext4\n"+
+		"DEVNAME=/dev/sdb2\n"+
+		`PARTLABEL=\$HOME\ \<\>\'\`+"`x\n"+
+		"\n")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, disks, map[string]map[string]any{
+		"/dev/sdb1": {
+			"label":      `a "b" c=d\ `,
+			"uuid":       "5d5d",
+			"block_size": 4096,
+			"type":       "ext4",
+		},
+		"/dev/sdb2": {
+			"partlabel": "$HOME <>'`x",
+		},
+	})
+}
+
+func TestParseBlkidExport_invalid(t *testing.T) {
+	gi := &GatherInvoker{context: testctx(t)}
+	for _, s := range []string{
+		"UUID=5d5d\n",                      // no DEVNAME
+		"DEVNAME=/dev/sda1\nUUID\n",        // no value
+		"DEVNAME=/dev/sda1\nuuid=5d5d\n",   // lowercase
+		"DEVNAME=/dev/sda1\nUUID=5d5d\\\n", // trailing backslash
+		"DEVNAME=/dev/sda1\n\nUUID=5d5d\n", // no DEVNAME after blank line
+		"DEVNAME=\n",
+	} {
+		_, err := parseBlkidExport(gi, s)
+		var ile *InvalidLineError
+		assert.Check(t, errors.As(err, &ile), "%q", s)
+	}
+}
+
+// escapeBlkid escapes s as blkid does.  It returns false if blkid
+// would encode s, which only blkid's -d option prevents.
+func escapeBlkid(s, esc string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 32 || c >= 127 {
+			return "", false
+		}
+		if strings.IndexByte(esc, c) >= 0 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), true
+}
+
+func FuzzParseBlkidExport(f *testing.F) {
+	f.Add(string(blkidExportOutput))
+	f.Add("DEVNAME=/dev/sda1\nLABEL=a\\ b=c\n")
+	f.Add("DEVNAME=/dev/sda1\nLABEL=x\\")
+	f.Add("LABEL=x\n")
+	f.Fuzz(func(t *testing.T, s string) {
+		gi := &GatherInvoker{context: testctx(t)}
+		disks, err := parseBlkidExport(gi, s)
+		if err != nil {
+			var ile *InvalidLineError
+			assert.Check(t, errors.As(err, &ile))
+			return
+		}
+		for device, attrs := range disks {
+			assert.Check(t, device != "")
+			for attr := range attrs {
+				assert.Check(t, attr == strings.ToLower(attr))
+			}
+		}
+	})
+}
+
+func FuzzParseBlkidExport_label(f *testing.F) {
+	f.Add("EFI System Partition")
+	f.Add(`a "b" c=d\ `)
+	f.Add("$HOME <>'`x")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, label string) {
+		escaped, ok := escapeBlkid(label, " \\\"'$`<>")
+		if !ok {
+			t.Skip()
+		}
+		gi := &GatherInvoker{context: testctx(t)}
+		disks, err := parseBlkidExport(gi,
+			"DEVNAME=/dev/sda1\nLABEL="+escaped+"\nTYPE=ext4\n")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, disks, map[string]map[string]any{
+			"/dev/sda1": {"label": label, "type": "ext4"},
+		})
+	})
+}
+
+func FuzzGatherDiskAttr(f *testing.F) {
+	for _, line := range strings.Split(string(blkidOutput), "\n") {
+		f.Add(line)
+	}
+	f.Add(`/dev/sda1: LABEL="a b=c" TYPE="ext4"`)
+	f.Add(`/dev/sda1: LABEL="x`)
+	f.Fuzz(func(t *testing.T, line string) {
+		gi := &GatherInvoker{context: testctx(t)}
+		var r HostInfo
+		if err := gatherDiskAttr(gi, &r, line); err != nil {
+			return
+		}
+		for _, attrs := range r.Disks {
+			for attr := range attrs {
+				assert.Check(t, attr == strings.ToLower(attr))
+			}
+		}
+	})
+}
+
+// The list parser only handles labels that don't contain "=".
+func FuzzGatherDiskAttr_label(f *testing.F) {
+	f.Add("EFI System Partition")
+	f.Add(`a "b" c\ `)
+	f.Add("")
+	f.Fuzz(func(t *testing.T, label string) {
+		escaped, ok := escapeBlkid(label, `"\`)
+		if !ok || strings.Contains(label, "=") {
+			t.Skip()
+		}
+		gi := &GatherInvoker{context: testctx(t)}
+		var r HostInfo
+		line := `/dev/sda1: LABEL="` + escaped + `" TYPE="ext4"`
+		assert.NilError(t, gatherDiskAttr(gi, &r, line))
+		assert.DeepEqual(t, r.Disks, map[string]map[string]any{
+			"/dev/sda1": {"label": label, "type": "ext4"},
+		})
+	})
+}
+
+func TestGatherDiskAttrs_parallelLUKS(t *testing.T) {
+	ci := &cannedInvoker{outputs: map[string][]byte{
+		"/sbin/blkid": []byte(`/dev/sda2: UUID="5d5d" TYPE="crypto_LUKS"
+/dev/sdb2: UUID="6e6e" TYPE="crypto_LUKS"
+/dev/sdc2: UUID="7f7f" TYPE="crypto_LUKS"
+/dev/sdc1: UUID="8B92-BD41" TYPE="vfat"
+`),
+		"cryptsetup luksDump /dev/sda2": luksdump,
+		"cryptsetup luksDump /dev/sdb2": luksdump,
+		"cryptsetup luksDump /dev/sdc2": luksdump,
+	}}
+
+	gi := &GatherInvoker{
+		context:     testctx(t),
+		invoker:     ci,
+		concurrency: 2,
+	}
+	var r HostInfo
+	assert.NilError(t, gatherDiskAttrs(gi, &r))
+	assert.Equal(t, ci.maxInFlight, 2)
+
+	for _, device := range []string{"/dev/sda2", "/dev/sdb2", "/dev/sdc2"} {
+		luks, ok := r.Disks[device]["luks"].(map[string]any)
+		assert.Assert(t, ok, device)
+		assert.Equal(t, luks["version"], 2)
+	}
+	assertNotHasKey(t, r.Disks["/dev/sdc1"], "luks")
+}
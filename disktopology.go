@@ -0,0 +1,373 @@
+package hostinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gatherDiskTopology gathers the block devices on the host and how
+// they are stacked, from `lsblk` or, if that fails, from sysfs.  Each
+// device's "parents" and "children" list the paths of the devices it
+// is built on and that are built on it, so partitions, encrypted
+// volumes and logical volumes can be traced back to physical disks.
+func gatherDiskTopology(gi *GatherInvoker, r *HostInfo) error {
+	disks, err1 := gatherLsblkTopology(gi)
+	if err1 == nil {
+		r.Disks = disks
+		return nil
+	}
+
+	gi.Logger().Debug().
+		AnErr("reason", err1).
+		Msg("Falling back to sysfs")
+
+	disks, err2 := gatherSysfsTopology(gi)
+	if err2 != nil {
+		return errors.Join(err1, err2)
+	}
+
+	r.Disks = disks
+	return nil
+}
+
+// A blockTopology accumulates block devices, keyed by path, and the
+// links between them.
+type blockTopology struct {
+	devices  map[string]map[string]any
+	parents  map[string]map[string]bool
+	children map[string]map[string]bool
+}
+
+func newBlockTopology() *blockTopology {
+	return &blockTopology{
+		devices:  make(map[string]map[string]any),
+		parents:  make(map[string]map[string]bool),
+		children: make(map[string]map[string]bool),
+	}
+}
+
+// device returns the attributes of the device at path, adding the
+// device if necessary.
+func (t *blockTopology) device(path string) map[string]any {
+	attrs, found := t.devices[path]
+	if !found {
+		attrs = make(map[string]any)
+		t.devices[path] = attrs
+	}
+	return attrs
+}
+
+// link records that the device at child is built on that at parent.
+func (t *blockTopology) link(parent, child string) {
+	addLink(t.parents, child, parent)
+	addLink(t.children, parent, child)
+}
+
+func addLink(links map[string]map[string]bool, from, to string) {
+	if links[from] == nil {
+		links[from] = make(map[string]bool)
+	}
+	links[from][to] = true
+}
+
+// disks returns the accumulated devices, with their links.
+func (t *blockTopology) disks() map[string]map[string]any {
+	for path, attrs := range t.devices {
+		if links := t.parents[path]; len(links) > 0 {
+			attrs["parents"] = slices.Sorted(maps.Keys(links))
+		}
+		if links := t.children[path]; len(links) > 0 {
+			attrs["children"] = slices.Sorted(maps.Keys(links))
+		}
+	}
+	return t.devices
+}
+
+// lsblkStrings maps `lsblk` columns with string values to the keys
+// gatherLsblkTopology stores them under.
+var lsblkStrings = map[string]string{
+	"kname":  "kname",
+	"type":   "device_type",
+	"model":  "model",
+	"serial": "serial",
+	"vendor": "vendor",
+	"wwn":    "wwn",
+	"tran":   "transport",
+}
+
+// lsblkBools maps `lsblk` columns with boolean values to the keys
+// gatherLsblkTopology stores them under.
+var lsblkBools = map[string]string{
+	"ro":   "read_only",
+	"rm":   "removable",
+	"rota": "rotational",
+}
+
+// gatherLsblkTopology gathers the output of `lsblk --json`.
+func gatherLsblkTopology(gi *GatherInvoker) (map[string]map[string]any, error) {
+	s, err := gi.Invoke("lsblk", "--json", "--bytes", "-O")
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Devices []map[string]any `json:"blockdevices"`
+	}
+	decoder := json.NewDecoder(bytes.NewBufferString(s))
+	decoder.UseNumber()
+	if err := decoder.Decode(&output); err != nil {
+		return nil, err
+	}
+
+	t := newBlockTopology()
+	for _, node := range output.Devices {
+		if err := t.addLsblkNode(node, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.disks(), nil
+}
+
+// addLsblkNode adds node, one device from the output of `lsblk`, and
+// its children to t.  The device is built on that at parent, unless
+// parent is empty.
+func (t *blockTopology) addLsblkNode(node map[string]any, parent string) error {
+	path, _ := node["path"].(string)
+	if path == "" {
+		name, _ := node["name"].(string)
+		if name == "" {
+			return errors.New("lsblk: device has no name")
+		}
+		path = "/dev/" + name
+	}
+
+	attrs := t.device(path)
+	for column, key := range lsblkStrings {
+		if s, ok := node[column].(string); ok {
+			if s = strings.TrimSpace(s); s != "" {
+				attrs[key] = s
+			}
+		}
+	}
+	for column, key := range lsblkBools {
+		if b, ok := lsblkBool(node[column]); ok {
+			attrs[key] = b
+		}
+	}
+	if n, ok := lsblkInt(node["size"]); ok {
+		attrs["size_bytes"] = n
+	}
+	if s, ok := node["maj:min"].(string); ok {
+		if major, minor, ok := parseMajorMinor(s); ok {
+			attrs["major"] = major
+			attrs["minor"] = minor
+		}
+	}
+
+	if parent != "" {
+		t.link(parent, path)
+	}
+
+	children, _ := node["children"].([]any)
+	for _, child := range children {
+		child, ok := child.(map[string]any)
+		if !ok {
+			return errors.New("lsblk: invalid children")
+		}
+		if err := t.addLsblkNode(child, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lsblkInt returns v as an int.  Older versions of `lsblk` output
+// numbers as strings.
+func lsblkInt(v any) (int, bool) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// lsblkBool returns v as a bool.  Older versions of `lsblk` output
+// booleans as "0" or "1".
+func lsblkBool(v any) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	n, ok := lsblkInt(v)
+	return n != 0, ok
+}
+
+// parseMajorMinor parses a "major:minor" device number.
+func parseMajorMinor(s string) (int, int, bool) {
+	major, minor, found := strings.Cut(strings.TrimSpace(s), ":")
+	if !found {
+		return 0, 0, false
+	}
+	majorNum, err1 := strconv.Atoi(major)
+	minorNum, err2 := strconv.Atoi(minor)
+	return majorNum, minorNum, err1 == nil && err2 == nil
+}
+
+// sysfsBlockDir is the sysfs directory listing every block device.
+const sysfsBlockDir = "/sys/class/block"
+
+// sysfsBools maps the sysfs files of block devices with boolean
+// values to the keys gatherSysfsTopology stores them under.
+var sysfsBools = map[string]string{
+	"ro":               "read_only",
+	"removable":        "removable",
+	"queue/rotational": "rotational",
+}
+
+// gatherSysfsTopology gathers the content of `/sys/class/block`.
+func gatherSysfsTopology(gi *GatherInvoker) (map[string]map[string]any, error) {
+	names, err := gi.ReadDir(sysfsBlockDir)
+	if err != nil {
+		return nil, err
+	}
+	isBlock := make(map[string]bool, len(names))
+	for _, name := range names {
+		isBlock[name] = true
+	}
+
+	// Links in sysfs are between kernel names.
+	paths := make(map[string]string, len(names))
+	var links [][2]string
+
+	t := newBlockTopology()
+	for _, name := range names {
+		dir := sysfsBlockDir + "/" + name
+		read := func(name string) (string, bool) {
+			s, err := gi.ReadFile(dir + "/" + name)
+			return strings.TrimSpace(s), err == nil
+		}
+
+		path := "/dev/" + name
+		if dmName, ok := read("dm/name"); ok && dmName != "" {
+			path = "/dev/mapper/" + dmName
+		}
+		paths[name] = path
+
+		attrs := t.device(path)
+		attrs["kname"] = name
+		attrs["device_type"] = sysfsDeviceType(name, read)
+
+		if s, ok := read("size"); ok {
+			if n, err := strconv.Atoi(s); err == nil {
+				attrs["size_bytes"] = n * 512 // always in sectors
+			}
+		}
+		if s, ok := read("dev"); ok {
+			if major, minor, ok := parseMajorMinor(s); ok {
+				attrs["major"] = major
+				attrs["minor"] = minor
+			}
+		}
+		for file, key := range sysfsBools {
+			if s, ok := read(file); ok {
+				attrs[key] = s == "1"
+			}
+		}
+		for _, key := range []string{"model", "serial", "vendor"} {
+			if s, ok := read("device/" + key); ok && s != "" {
+				attrs[key] = s
+			}
+		}
+
+		slaves, _ := gi.ReadDir(dir + "/slaves")
+		for _, slave := range slaves {
+			links = append(links, [2]string{slave, name})
+		}
+
+		if attrs["device_type"] == "part" {
+			continue
+		}
+		entries, _ := gi.ReadDir(dir)
+		for _, entry := range entries {
+			if entry != name && isBlock[entry] {
+				links = append(links, [2]string{name, entry})
+			}
+		}
+	}
+
+	for _, link := range links {
+		parent, found1 := paths[link[0]]
+		child, found2 := paths[link[1]]
+		if found1 && found2 {
+			t.link(parent, child)
+		}
+	}
+
+	return t.disks(), nil
+}
+
+// sysfsDeviceType returns the type of the block device with the given
+// kernel name, using the same names as `lsblk`.
+func sysfsDeviceType(name string, read func(string) (string, bool)) string {
+	if _, ok := read("partition"); ok {
+		return "part"
+	}
+	if uuid, ok := read("dm/uuid"); ok {
+		switch {
+		case strings.HasPrefix(uuid, "CRYPT-"):
+			return "crypt"
+		case strings.HasPrefix(uuid, "LVM-"):
+			return "lvm"
+		}
+		return "dm"
+	}
+	if strings.HasPrefix(name, "loop") {
+		return "loop"
+	}
+	return "disk"
+}
+
+// PhysicalDisks returns the paths of the devices that the block device
+// at path is ultimately built on, according to the topology gathered
+// by the "DiskTopology" gatherer.  A device with no parents is its own
+// physical disk.  The result is nil if path is not in r.Disks.
+func (r *HostInfo) PhysicalDisks(path string) []string {
+	if _, found := r.Disks[path]; !found {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	var walk func(string)
+	walk = func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+
+		parents := asStrings(r.Disks[path]["parents"])
+		if len(parents) == 0 {
+			result = append(result, path)
+			return
+		}
+		for _, parent := range parents {
+			walk(parent)
+		}
+	}
+	walk(path)
+
+	slices.Sort(result)
+	return result
+}
@@ -0,0 +1,269 @@
+package hostinfo
+
+import (
+	_ "embed"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGatherDiskTopology_live(t *testing.T) {
+	r := assertExec(t, gatherDiskTopology)
+	for device, attrs := range r.Disks {
+		assert.Check(t, attrs["device_type"] != nil, device)
+		for _, parent := range asStrings(attrs["parents"]) {
+			assert.Check(t, r.Disks[parent] != nil, parent)
+		}
+	}
+}
+
+//go:embed resources/lsblk.json
+var testLsblkOutput []byte
+
+func TestGatherDiskTopology(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns(testLsblkOutput, nil)
+
+	r := assertMock(t, gatherDiskTopology, mock)
+	assert.DeepEqual(t, slices.Sorted(maps.Keys(r.Disks)), []string{
+		"/dev/mapper/nvme0n1p3_crypt",
+		"/dev/mapper/vgubuntu-root",
+		"/dev/mapper/vgubuntu-swap_1",
+		"/dev/nvme0n1",
+		"/dev/nvme0n1p1",
+		"/dev/nvme0n1p2",
+		"/dev/nvme0n1p3",
+		"/dev/sda",
+	})
+
+	assert.DeepEqual(t, r.Disks["/dev/nvme0n1"], map[string]any{
+		"kname":       "nvme0n1",
+		"device_type": "disk",
+		"size_bytes":  512110190592,
+		"major":       259,
+		"minor":       0,
+		"read_only":   false,
+		"removable":   false,
+		"rotational":  false,
+		"model":       "SAMSUNG MZVL2512HCJQ-00BL7",
+		"serial":      "S64KNX0T123456",
+		"wwn":         "eui.002538b111b22c33",
+		"transport":   "nvme",
+		"children": []string{
+			"/dev/nvme0n1p1",
+			"/dev/nvme0n1p2",
+			"/dev/nvme0n1p3",
+		},
+	})
+
+	crypt := r.Disks["/dev/mapper/nvme0n1p3_crypt"]
+	assert.Equal(t, crypt["kname"], "dm-0")
+	assert.Equal(t, crypt["device_type"], "crypt")
+	assert.DeepEqual(t, crypt["parents"], []string{"/dev/nvme0n1p3"})
+	assert.DeepEqual(t, crypt["children"], []string{
+		"/dev/mapper/vgubuntu-root",
+		"/dev/mapper/vgubuntu-swap_1",
+	})
+
+	sda := r.Disks["/dev/sda"]
+	assert.Equal(t, sda["vendor"], "SanDisk")
+	assert.Equal(t, sda["removable"], true)
+	assertNotHasKey(t, sda, "wwn")
+	assertNotHasKey(t, sda, "children")
+}
+
+// Older versions of lsblk have no "path" column, and output every
+// value as a string.
+func TestGatherDiskTopology_oldLsblk(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns([]byte(`{
+   "blockdevices": [
+      {"name": "sda", "maj:min": "8:0", "rm": "0", "size": "500107862016", "ro": "0", "type": "disk", "rota": "1",
+         "children": [
+            {"name": "sda1", "maj:min": "8:1", "rm": "0", "size": "500106813440", "ro": "1", "type": "part", "rota": "1"}
+         ]
+      }
+   ]
+}`), nil)
+
+	r := assertMock(t, gatherDiskTopology, mock)
+	assert.DeepEqual(t, r.Disks, map[string]map[string]any{
+		"/dev/sda": {
+			"device_type": "disk",
+			"size_bytes":  500107862016,
+			"major":       8,
+			"minor":       0,
+			"read_only":   false,
+			"removable":   false,
+			"rotational":  true,
+			"children":    []string{"/dev/sda1"},
+		},
+		"/dev/sda1": {
+			"device_type": "part",
+			"size_bytes":  500106813440,
+			"major":       8,
+			"minor":       1,
+			"read_only":   true,
+			"removable":   false,
+			"rotational":  true,
+			"parents":     []string{"/dev/sda"},
+		},
+	})
+}
+
+func TestGatherDiskTopology_sysfs(t *testing.T) {
+	file := func(s string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(s + "\n")}
+	}
+	fsys := fstest.MapFS{
+		"sys/class/block/sda/size":             file("1000215216"),
+		"sys/class/block/sda/dev":              file("8:0"),
+		"sys/class/block/sda/ro":               file("0"),
+		"sys/class/block/sda/removable":        file("0"),
+		"sys/class/block/sda/queue/rotational": file("1"),
+		"sys/class/block/sda/device/model":     file("ST500DM002-1BD14 "),
+		"sys/class/block/sda/device/vendor":    file("ATA     "),
+		"sys/class/block/sda/sda1/partition":   file("1"),
+
+		"sys/class/block/sda1/size":      file("1000212480"),
+		"sys/class/block/sda1/dev":       file("8:1"),
+		"sys/class/block/sda1/partition": file("1"),
+
+		"sys/class/block/dm-0/size":          file("1000179712"),
+		"sys/class/block/dm-0/dev":           file("252:0"),
+		"sys/class/block/dm-0/dm/name":       file("sda1_crypt"),
+		"sys/class/block/dm-0/dm/uuid":       file("CRYPT-LUKS2-242e637a-sda1_crypt"),
+		"sys/class/block/dm-0/slaves/sda1":   file(""),
+		"sys/class/block/loop0/size":         file("0"),
+		"sys/class/block/loop0/dev":          file("7:0"),
+		"sys/class/block/loop0/holders/none": file(""),
+	}
+
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns(nil, errors.New("exec: \"lsblk\": executable file not found in $PATH"))
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock, fsys: fsys}
+	var r HostInfo
+	assert.NilError(t, gatherDiskTopology(gi, &r))
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	assert.DeepEqual(t, r.Disks, map[string]map[string]any{
+		"/dev/sda": {
+			"kname":       "sda",
+			"device_type": "disk",
+			"size_bytes":  512110190592,
+			"major":       8,
+			"minor":       0,
+			"read_only":   false,
+			"removable":   false,
+			"rotational":  true,
+			"model":       "ST500DM002-1BD14",
+			"vendor":      "ATA",
+			"children":    []string{"/dev/sda1"},
+		},
+		"/dev/sda1": {
+			"kname":       "sda1",
+			"device_type": "part",
+			"size_bytes":  512108789760,
+			"major":       8,
+			"minor":       1,
+			"parents":     []string{"/dev/sda"},
+			"children":    []string{"/dev/mapper/sda1_crypt"},
+		},
+		"/dev/mapper/sda1_crypt": {
+			"kname":       "dm-0",
+			"device_type": "crypt",
+			"size_bytes":  512092012544,
+			"major":       252,
+			"minor":       0,
+			"parents":     []string{"/dev/sda1"},
+		},
+		"/dev/loop0": {
+			"kname":       "loop0",
+			"device_type": "loop",
+			"size_bytes":  0,
+			"major":       7,
+			"minor":       0,
+		},
+	})
+}
+
+func TestGatherDiskTopology_failed(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("ls", "-1A", "--", "/sys/class/block").
+		Returns(nil, errors.New("exit status 2"))
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock}
+	var r HostInfo
+	err := gatherDiskTopology(gi, &r)
+	assert.ErrorContains(t, err, "exit status 1\nexit status 2")
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Check(t, r.Disks == nil)
+}
+
+func TestGatherInvokerReadDir(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("ls", "-1A", "--", "/sys/class/block").
+		Returns([]byte("sda1\nsda\nloop0\n"), nil)
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock}
+	names, err := gi.ReadDir("/sys/class/block")
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.DeepEqual(t, names, []string{"loop0", "sda", "sda1"})
+
+	gi.fsys = fstest.MapFS{
+		"root/sys/class/block/sda/dev": {},
+		"root/sys/class/block/dm-0":    {},
+	}
+	gi.root = "/root"
+	names, err = gi.ReadDir("/sys/class/block")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"dm-0", "sda"})
+}
+
+// TestPhysicalDisks checks that the LUKS headers gathered from blkid
+// can be tied to the physical disks they are stored on.
+func TestPhysicalDisks(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("lsblk", "--json", "--bytes", "-O").
+		Returns(testLsblkOutput, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("DiskAttrs", "DiskTopology"),
+		Concurrency(1),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	luks := r.Disks["/dev/nvme0n1p3"]
+	assert.Equal(t, luks["type"], "crypto_LUKS")
+	assert.Equal(t, luks["device_type"], "part")
+	assert.Check(t, luks["luks"] != nil)
+
+	assert.DeepEqual(t, r.PhysicalDisks("/dev/nvme0n1p3"),
+		[]string{"/dev/nvme0n1"})
+	assert.DeepEqual(t, r.PhysicalDisks("/dev/mapper/vgubuntu-root"),
+		[]string{"/dev/nvme0n1"})
+	assert.DeepEqual(t, r.PhysicalDisks("/dev/sda"), []string{"/dev/sda"})
+	assert.Check(t, r.PhysicalDisks("/dev/sdz") == nil)
+
+	bd := typedBlockDevice("/dev/nvme0n1p3", luks)
+	assert.Equal(t, bd.DeviceType, "part")
+	assert.Equal(t, bd.SizeBytes, uint64(509423812608))
+	assert.DeepEqual(t, bd.Parents, []string{"/dev/nvme0n1"})
+	assert.DeepEqual(t, bd.Children, []string{"/dev/mapper/nvme0n1p3_crypt"})
+}
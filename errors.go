@@ -1,6 +1,11 @@
 package hostinfo
 
-import "fmt"
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 // An InvalidLineError is returned by parsers encountering invalid input.
 type InvalidLineError struct {
@@ -11,3 +16,64 @@ type InvalidLineError struct {
 func (e *InvalidLineError) Error() string {
 	return fmt.Sprintf("%s: %q: invalid line", e.Prefix, e.Line)
 }
+
+// An UnknownGathererError is returned by [Gather] when an [Option]
+// names a gatherer that does not exist.
+type UnknownGathererError struct {
+	Name string
+}
+
+// Error implements the error interface.
+func (e *UnknownGathererError) Error() string {
+	return fmt.Sprintf("%q: unknown gatherer", e.Name)
+}
+
+// A GatherError reports which gatherers failed during [Gather], and
+// why.  It is returned by Gather if every gatherer failed, and is
+// stored in [HostInfo.Partial] if only some did.
+type GatherError struct {
+	// Errors maps the names of the failed gatherers, as accepted by
+	// [Only] and [Skip], to the errors they returned.
+	Errors map[string]error
+}
+
+// Error implements the error interface.
+func (e *GatherError) Error() string {
+	var b strings.Builder
+	for i, name := range slices.Sorted(maps.Keys(e.Errors)) {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s: %v", name, e.Errors[name])
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors returned by the failed gatherers, for
+// use with [errors.Is] and [errors.As].
+func (e *GatherError) Unwrap() []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(e.Errors)) {
+		errs = append(errs, e.Errors[name])
+	}
+	return errs
+}
+
+// add records that the named gatherer failed with err.
+func (e *GatherError) add(name string, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[string]error)
+	}
+	e.Errors[name] = err
+}
+
+// An UnexpectedCommandError is returned by a [Replayer] asked to run
+// a command it has no recording for.
+type UnexpectedCommandError struct {
+	Command []string
+}
+
+// Error implements the error interface.
+func (e *UnexpectedCommandError) Error() string {
+	return fmt.Sprintf("%q: unexpected command", e.Command)
+}
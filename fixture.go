@@ -0,0 +1,176 @@
+package hostinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"unicode/utf8"
+
+	"gbenson.net/go/invoker"
+)
+
+// A Fixture is a record of the commands run on a host, and of their
+// results, for replaying in tests.  Fixtures are created by recording
+// a [Gather] with a [Recorder], and are replayed with a [Replayer].
+type Fixture struct {
+	Invocations []*Invocation `json:"invocations"`
+}
+
+// An Invocation records a single command and its result.
+type Invocation struct {
+	// Command is the command that was run, and its arguments.
+	Command []string `json:"command"`
+
+	// Stdout is what the command wrote to its standard output, if
+	// that was valid UTF-8, or StdoutBase64 if it was not.
+	Stdout       string `json:"stdout,omitempty"`
+	StdoutBase64 []byte `json:"stdout_base64,omitempty"`
+
+	// Error is the text of the error the command returned, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// newInvocation returns an Invocation recording the given result.
+func newInvocation(command []string, out []byte, err error) *Invocation {
+	inv := &Invocation{Command: command}
+	if utf8.Valid(out) {
+		inv.Stdout = string(out)
+	} else {
+		inv.StdoutBase64 = out
+	}
+	if err != nil {
+		inv.Error = err.Error()
+	}
+	return inv
+}
+
+// Result returns the recorded result of the invocation.
+func (inv *Invocation) Result() ([]byte, error) {
+	out := inv.StdoutBase64
+	if out == nil && inv.Stdout != "" {
+		out = []byte(inv.Stdout)
+	}
+	if inv.Error != "" {
+		return out, errors.New(inv.Error)
+	}
+	return out, nil
+}
+
+// LoadFixture reads a fixture from the named file.
+func LoadFixture(filename string) (*Fixture, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return &f, nil
+}
+
+// Save writes the fixture to the named file.
+func (f *Fixture) Save(filename string) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0666)
+}
+
+// A Recorder is an [invoker.Invoker] that runs commands using another
+// invoker, recording each command and its result.  Note that [Gather]
+// reads files using `cat` when using a Recorder, so that they too are
+// recorded.
+type Recorder struct {
+	invoker invoker.Invoker
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder returns a [Recorder] that runs commands using inv.
+func NewRecorder(inv invoker.Invoker) *Recorder {
+	return &Recorder{invoker: inv}
+}
+
+// Invoke implements [invoker.Invoker].
+func (r *Recorder) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	out, err := r.invoker.Invoke(ctx, name, arg...)
+
+	command := append([]string{name}, arg...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Invocations = append(
+		r.fixture.Invocations,
+		newInvocation(command, out, err),
+	)
+
+	return out, err
+}
+
+// Fixture returns a copy of everything recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Fixture{slices.Clone(r.fixture.Invocations)}
+}
+
+// A Replayer is an [invoker.Invoker] that replays the results in a
+// [Fixture].  Each recorded invocation is replayed once, in response
+// to the same command.  Commands that were recorded more than once
+// are replayed in the order they were recorded.  Commands that were
+// not recorded, or whose recordings have all been replayed, return
+// an [UnexpectedCommandError].
+type Replayer struct {
+	mu      sync.Mutex
+	pending []*Invocation
+}
+
+// NewReplayer returns a [Replayer] that replays f.
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{pending: slices.Clone(f.Invocations)}
+}
+
+// Invoke implements [invoker.Invoker].
+func (r *Replayer) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	command := append([]string{name}, arg...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, inv := range r.pending {
+		if slices.Equal(inv.Command, command) {
+			r.pending = slices.Delete(r.pending, i, i+1)
+			return inv.Result()
+		}
+	}
+
+	return nil, &UnexpectedCommandError{command}
+}
+
+// Pending returns the recorded commands that have not been replayed.
+func (r *Replayer) Pending() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result [][]string
+	for _, inv := range r.pending {
+		result = append(result, inv.Command)
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package hostinfo
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("sudo", "cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("cat", "/proc/cpuinfo").
+		Returns(x86CPUInfo, nil)
+	mock.ExpectInvoke("ip", "--json", "address", "show").
+		Returns(nil, errors.New("exit status 255"))
+	mock.ExpectInvoke("ip", "address", "show").
+		Returns(testInterfacesNoJSON, nil)
+
+	opts := []Option{
+		Only("DiskAttrs", "CPUInfo", "Interfaces"),
+		Concurrency(1),
+	}
+
+	recorder := NewRecorder(mock)
+	want, err := Gather(testctx(t), recorder, opts...)
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	filename := filepath.Join(t.TempDir(), "fixture.json")
+	assert.NilError(t, recorder.Fixture().Save(filename))
+
+	fixture, err := LoadFixture(filename)
+	assert.NilError(t, err)
+	assert.Equal(t, len(fixture.Invocations), 6)
+
+	replayer := NewReplayer(fixture)
+	got, err := Gather(testctx(t), replayer, opts...)
+	assert.NilError(t, err)
+	assert.Equal(t, len(replayer.Pending()), 0)
+
+	assert.Equal(t, len(got.Disks), 6)
+	assert.Equal(t, len(got.CPUs), 12)
+	assert.Equal(t, len(got.Interfaces), 3)
+
+	// Timings differ between runs.
+	got.Meta, want.Meta = nil, nil
+	assertJSONEqual(t, got, want)
+}
+
+func TestReplayer_binary(t *testing.T) {
+	want := []byte{0x06, 0x00, 0x00, 0x00, 0x01, 0xff}
+	recorder := NewRecorder(&cannedInvoker{outputs: map[string][]byte{
+		"cat /binary": want,
+	}})
+	_, err := recorder.Invoke(testctx(t), "cat", "/binary")
+	assert.NilError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "fixture.json")
+	assert.NilError(t, recorder.Fixture().Save(filename))
+	fixture, err := LoadFixture(filename)
+	assert.NilError(t, err)
+
+	got, err := NewReplayer(fixture).Invoke(testctx(t), "cat", "/binary")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got, want)
+}
+
+func TestReplayer_unexpected(t *testing.T) {
+	replayer := NewReplayer(&Fixture{[]*Invocation{{
+		Command: []string{"cat", "/etc/machine-id"},
+		Stdout:  "0123456789abcdef0123456789abcdef\n",
+	}}})
+
+	_, err := replayer.Invoke(testctx(t), "cat", "/etc/hostname")
+	var uce *UnexpectedCommandError
+	assert.Assert(t, errors.As(err, &uce))
+	assert.Error(t, err, `["cat" "/etc/hostname"]: unexpected command`)
+
+	out, err := replayer.Invoke(testctx(t), "cat", "/etc/machine-id")
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "0123456789abcdef0123456789abcdef\n")
+
+	_, err = replayer.Invoke(testctx(t), "cat", "/etc/machine-id")
+	assert.Check(t, errors.As(err, &uce))
+}
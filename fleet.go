@@ -0,0 +1,193 @@
+package hostinfo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gbenson.net/go/invoker"
+)
+
+// A HostResult is the result of gathering a single host with
+// [GatherMany].  Exactly one of HostInfo and Err is non-nil.
+type HostResult struct {
+	HostInfo *HostInfo
+	Err      error
+}
+
+// MarshalJSON implements [json.Marshaler].  Results serialize as
+// objects with either a "hostinfo" or an "error" member.
+func (hr *HostResult) MarshalJSON() ([]byte, error) {
+	var v struct {
+		HostInfo *HostInfo `json:"hostinfo,omitempty"`
+		Error    string    `json:"error,omitempty"`
+	}
+	v.HostInfo = hr.HostInfo
+	if hr.Err != nil {
+		v.Error = hr.Err.Error()
+	}
+	return json.Marshal(&v)
+}
+
+// GatherMany calls [Gather] for each host in hosts, which maps host
+// names to the invokers used to gather them.  Several hosts are
+// gathered at once; use [HostConcurrency] to limit the parallelism,
+// and [HostTimeout] to limit the time each host may take.  Other
+// options are passed to Gather.  The result maps each host name in
+// hosts to the result of gathering that host.
+func GatherMany(
+	ctx context.Context,
+	hosts map[string]invoker.Invoker,
+	opts ...Option,
+) map[string]*HostResult {
+	o := newOptions(opts)
+
+	names := slices.Sorted(maps.Keys(hosts))
+	results := make([]*HostResult, len(names))
+
+	parallel(len(names), o.hostConcurrency, func(i int) {
+		ctx := ctx
+		if o.hostTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, o.hostTimeout)
+			defer cancel()
+		}
+
+		r, err := Gather(ctx, hosts[names[i]], opts...)
+		results[i] = &HostResult{r, err}
+	})
+
+	m := make(map[string]*HostResult, len(names))
+	for i, name := range names {
+		m[name] = results[i]
+	}
+	return m
+}
+
+// LoadInventory reads an inventory from the named file.  See
+// [ReadInventory] for the format.
+func LoadInventory(
+	filename string,
+	defaults *SSHInvoker,
+) (map[string]invoker.Invoker, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadInventory(f, defaults)
+}
+
+// ReadInventory reads an inventory, suitable for passing to
+// [GatherMany], from r.  Each line of the input names one host, in
+// the form "[user@]host[:port]", optionally followed by one or more
+// space-separated settings:
+//
+//	jump=HOST[,HOST...]  connect through the given jump hosts
+//	identity=FILE        authenticate with the given private key
+//
+// Blank lines and lines starting with "#" are ignored.  The host
+// "localhost" is gathered using [invoker.Exec], and all other hosts
+// using an [SSHInvoker] copied from defaults, if non-nil.
+func ReadInventory(
+	r io.Reader,
+	defaults *SSHInvoker,
+) (map[string]invoker.Invoker, error) {
+	hosts := make(map[string]invoker.Invoker)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		name := fields[0]
+		if _, found := hosts[name]; found {
+			return nil, inventoryError(line)
+		}
+
+		if name == "localhost" {
+			if len(fields) > 1 {
+				return nil, inventoryError(line)
+			}
+			hosts[name] = invoker.Exec
+			continue
+		}
+
+		si, err := parseInventoryHost(defaults, fields)
+		if err != nil {
+			return nil, inventoryError(line)
+		}
+		hosts[name] = si
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
+func inventoryError(line string) error {
+	return &InvalidLineError{"inventory", line}
+}
+
+// parseInventoryHost parses the fields of a line of an inventory.
+func parseInventoryHost(
+	defaults *SSHInvoker,
+	fields []string,
+) (*SSHInvoker, error) {
+	si := &SSHInvoker{}
+	if defaults != nil {
+		*si = *defaults
+		si.JumpHosts = slices.Clone(si.JumpHosts)
+		si.Options = slices.Clone(si.Options)
+	}
+
+	host := fields[0]
+	if user, rest, found := strings.Cut(host, "@"); found {
+		if user == "" {
+			return nil, fmt.Errorf("%q: empty user", host)
+		}
+		si.User = user
+		host = rest
+	}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, err
+		}
+		host, si.Port = h, n
+	}
+	if host == "" {
+		return nil, fmt.Errorf("%q: empty host", fields[0])
+	}
+	si.Host = host
+
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("%q: invalid setting", field)
+		}
+		switch key {
+		case "jump":
+			si.JumpHosts = strings.Split(value, ",")
+		case "identity":
+			si.IdentityFile = value
+		default:
+			return nil, fmt.Errorf("%q: unknown setting", field)
+		}
+	}
+
+	return si, nil
+}
@@ -0,0 +1,110 @@
+package hostinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGatherMany(t *testing.T) {
+	good := &cannedInvoker{outputs: map[string][]byte{
+		"cat /proc/meminfo":   testMemInfo,
+		"cat /etc/os-release": fedoraOSRelease,
+	}}
+	bad := &cannedInvoker{}
+	wedged := newWedgedInvoker(t, good, "cat /proc/meminfo")
+
+	start := time.Now()
+	results := GatherMany(testctx(t),
+		map[string]invoker.Invoker{
+			"good":   good,
+			"bad":    bad,
+			"wedged": wedged,
+		},
+		Only("MemInfo", "OSRelease"),
+		HostConcurrency(2),
+		HostTimeout(100*time.Millisecond))
+	assert.Check(t, time.Since(start) < time.Second)
+	assert.Equal(t, len(results), 3)
+
+	r := results["good"]
+	assert.NilError(t, r.Err)
+	assert.Equal(t, len(r.HostInfo.Memory), 55)
+	assert.Equal(t, r.HostInfo.OS["id"], "fedora")
+
+	r = results["bad"]
+	assert.Check(t, r.HostInfo == nil)
+	var ge *GatherError
+	assert.Check(t, errors.As(r.Err, &ge))
+
+	r = results["wedged"]
+	assert.NilError(t, r.Err)
+	assert.Equal(t, r.HostInfo.OS["id"], "fedora")
+	assert.Check(t, errors.Is(r.HostInfo.Partial, context.DeadlineExceeded))
+
+	b, err := json.Marshal(results)
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(string(b),
+		`{"bad":{"error":"MemInfo: cat /proc/meminfo: unexpected command;`))
+}
+
+func TestReadInventory(t *testing.T) {
+	hosts, err := ReadInventory(strings.NewReader(`
+# Comments and blank lines are ignored
+
+localhost
+db1.example.com
+  admin@db2.example.com:2222   jump=bastion,10.0.0.1:22
+[fd00::1]:2022 identity=/etc/hostinfo/id_ed25519
+`), &SSHInvoker{
+		User:           "inventory",
+		KnownHostsFile: "/etc/hostinfo/known_hosts",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(hosts), 4)
+
+	assert.Equal(t, hosts["localhost"], invoker.Exec)
+
+	assert.DeepEqual(t, hosts["db1.example.com"], &SSHInvoker{
+		Host:           "db1.example.com",
+		User:           "inventory",
+		KnownHostsFile: "/etc/hostinfo/known_hosts",
+	})
+
+	assert.DeepEqual(t, hosts["admin@db2.example.com:2222"], &SSHInvoker{
+		Host:           "db2.example.com",
+		User:           "admin",
+		Port:           2222,
+		KnownHostsFile: "/etc/hostinfo/known_hosts",
+		JumpHosts:      []string{"bastion", "10.0.0.1:22"},
+	})
+
+	assert.DeepEqual(t, hosts["[fd00::1]:2022"], &SSHInvoker{
+		Host:           "fd00::1",
+		User:           "inventory",
+		Port:           2022,
+		IdentityFile:   "/etc/hostinfo/id_ed25519",
+		KnownHostsFile: "/etc/hostinfo/known_hosts",
+	})
+}
+
+func TestReadInventory_invalid(t *testing.T) {
+	for _, line := range []string{
+		"host1\nhost1",
+		"@host1",
+		"host1:ssh",
+		"host1 jump",
+		"host1 port=22",
+		"localhost jump=bastion",
+	} {
+		_, err := ReadInventory(strings.NewReader(line), nil)
+		var ile *InvalidLineError
+		assert.Check(t, errors.As(err, &ile), line)
+	}
+}
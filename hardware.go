@@ -0,0 +1,153 @@
+package hostinfo
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// dmiFields are the files in "/sys/class/dmi/id" that gatherHardware
+// reads.  Fields with dmidecode keywords are readable only by root,
+// and are retrieved with `dmidecode` if they cannot be read.
+var dmiFields = []struct {
+	name    string
+	keyword string
+}{
+	{"sys_vendor", ""},
+	{"product_name", ""},
+	{"product_version", ""},
+	{"product_family", ""},
+	{"product_sku", ""},
+	{"product_serial", "system-serial-number"},
+	{"product_uuid", "system-uuid"},
+	{"board_vendor", ""},
+	{"board_name", ""},
+	{"board_version", ""},
+	{"board_serial", "baseboard-serial-number"},
+	{"board_asset_tag", ""},
+	{"bios_vendor", ""},
+	{"bios_version", ""},
+	{"bios_date", ""},
+	{"bios_release", ""},
+	{"chassis_vendor", ""},
+	{"chassis_type", ""},
+	{"chassis_version", ""},
+	{"chassis_serial", "chassis-serial-number"},
+	{"chassis_asset_tag", ""},
+}
+
+// gatherHardware gathers the content of `/sys/class/dmi/id`, which
+// identifies the physical or virtual machine.  Fields which only root
+// can read are retrieved using `dmidecode`, with sudo if necessary.
+// Chassis types are reported using the names dmidecode uses.
+func gatherHardware(gi *GatherInvoker, r *HostInfo) error {
+	hw := make(map[string]string)
+
+	var errs []error
+	for _, field := range dmiFields {
+		value, err := readDMIField(gi, field.name, field.keyword)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		} else if value == "" {
+			continue
+		}
+		if field.name == "chassis_type" {
+			value = chassisTypeName(value)
+		}
+		hw[field.name] = value
+	}
+
+	if len(hw) == 0 {
+		return errors.Join(errs...)
+	}
+
+	r.Hardware = hw
+	return nil
+}
+
+// readDMIField returns the named DMI field, retrieving it with
+// `dmidecode -s keyword` if it cannot be read and keyword is not
+// empty.  An error is returned if the field could not be retrieved
+// either way; gatherHardware only fails if no field is retrieved.
+func readDMIField(gi *GatherInvoker, name, keyword string) (string, error) {
+	s, err1 := gi.ReadFile("/sys/class/dmi/id/" + name)
+	if err1 == nil {
+		return strings.TrimSpace(s), nil
+	} else if keyword == "" {
+		gi.Logger().Debug().
+			Str("field", name).
+			AnErr("reason", err1).
+			Msg("Skipping")
+		return "", err1
+	}
+
+	s, err2 := gi.InvokeRetrySudo("dmidecode", "-s", keyword)
+	if err2 != nil {
+		gi.Logger().Debug().
+			Str("field", name).
+			AnErr("reason", err2).
+			Msg("Skipping")
+		return "", errors.Join(err1, err2)
+	}
+
+	// dmidecode reports problems as comments.
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// chassisTypes are the names of SMBIOS chassis types, indexed by
+// type number.
+var chassisTypes = []string{
+	1:  "Other",
+	2:  "Unknown",
+	3:  "Desktop",
+	4:  "Low Profile Desktop",
+	5:  "Pizza Box",
+	6:  "Mini Tower",
+	7:  "Tower",
+	8:  "Portable",
+	9:  "Laptop",
+	10: "Notebook",
+	11: "Hand Held",
+	12: "Docking Station",
+	13: "All In One",
+	14: "Sub Notebook",
+	15: "Space-saving",
+	16: "Lunch Box",
+	17: "Main Server Chassis",
+	18: "Expansion Chassis",
+	19: "Sub Chassis",
+	20: "Bus Expansion Chassis",
+	21: "Peripheral Chassis",
+	22: "RAID Chassis",
+	23: "Rack Mount Chassis",
+	24: "Sealed-case PC",
+	25: "Multi-system",
+	26: "CompactPCI",
+	27: "AdvancedTCA",
+	28: "Blade",
+	29: "Blade Enclosing",
+	30: "Tablet",
+	31: "Convertible",
+	32: "Detachable",
+	33: "IoT Gateway",
+	34: "Embedded PC",
+	35: "Mini PC",
+	36: "Stick PC",
+}
+
+// chassisTypeName returns the name of the given SMBIOS chassis type,
+// or s if it is not a known type number.
+func chassisTypeName(s string) string {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n >= len(chassisTypes) {
+		return s
+	}
+	return chassisTypes[n]
+}
@@ -0,0 +1,74 @@
+package hostinfo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGatherHardware_live(t *testing.T) {
+	if _, err := os.Stat("/sys/class/dmi/id"); err != nil {
+		t.Skip(err)
+	}
+	r := assertExec(t, gatherHardware)
+	assert.Check(t, len(r.Hardware) > 0)
+}
+
+func TestGatherHardware(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sys/class/dmi/id/sys_vendor":   {Data: []byte("LENOVO\n")},
+		"sys/class/dmi/id/product_name": {Data: []byte("21AH00BUUK\n")},
+		"sys/class/dmi/id/bios_version": {Data: []byte("N3MET18W (1.17 )\n")},
+		"sys/class/dmi/id/chassis_type": {Data: []byte("10\n")},
+		"sys/class/dmi/id/board_name":   {Data: []byte("\n")},
+	}
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("dmidecode", "-s", "system-serial-number").
+		Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("sudo", "dmidecode", "-s", "system-serial-number").
+		Returns([]byte("PF3ABCDE\n"), nil)
+	mock.ExpectInvoke("dmidecode", "-s", "system-uuid").
+		Returns([]byte("# No SMBIOS nor DMI entry point found, sorry.\n"), nil)
+	mock.ExpectInvoke("dmidecode", "-s", "baseboard-serial-number").
+		Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("sudo", "dmidecode", "-s", "baseboard-serial-number").
+		Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("dmidecode", "-s", "chassis-serial-number").
+		Returns([]byte("PF3ABCDE\n"), nil)
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock, fsys: fsys}
+	var r HostInfo
+	assert.NilError(t, gatherHardware(gi, &r))
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	assert.DeepEqual(t, r.Hardware, map[string]string{
+		"sys_vendor":     "LENOVO",
+		"product_name":   "21AH00BUUK",
+		"product_serial": "PF3ABCDE",
+		"bios_version":   "N3MET18W (1.17 )",
+		"chassis_type":   "Notebook",
+		"chassis_serial": "PF3ABCDE",
+	})
+}
+
+func TestGatherHardware_none(t *testing.T) {
+	ci := &cannedInvoker{}
+	gi := &GatherInvoker{context: testctx(t), invoker: ci, fsys: fstest.MapFS{}}
+	var r HostInfo
+	err := gatherHardware(gi, &r)
+	assert.Check(t, errors.Is(err, fs.ErrNotExist))
+	assert.Check(t, r.Hardware == nil)
+}
+
+func TestChassisTypeName(t *testing.T) {
+	assert.Equal(t, chassisTypeName("3"), "Desktop")
+	assert.Equal(t, chassisTypeName("36"), "Stick PC")
+	assert.Equal(t, chassisTypeName("0"), "0")
+	assert.Equal(t, chassisTypeName("99"), "99")
+	assert.Equal(t, chassisTypeName("Laptop"), "Laptop")
+}
@@ -4,9 +4,14 @@ package hostinfo
 import (
 	"context"
 	"errors"
+	"io/fs"
+	"os"
+	"path"
 	"reflect"
-	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"gbenson.net/go/invoker"
 	"gbenson.net/go/logger"
@@ -18,86 +23,319 @@ import (
 // then either embed/alias the type and add accessor methods, or
 // define their own types with the required fields and types.
 type HostInfo struct {
-	// Disks is constructed from the output of `blkid` and `cryptsetup`.
+	// SchemaVersion is the version of the serialization format,
+	// which [Gather] sets to [SchemaVersion].  See [Validate].
+	SchemaVersion string `json:"schema_version,omitempty"`
+
+	// Disks is constructed from the output of `blkid`, `cryptsetup`
+	// and `lsblk`.
 	Disks map[string]map[string]any `json:"block_devices,omitempty"`
 
 	// CPUs and CPUInfo are the contents of "/proc/cpuinfo".
 	CPUs    []map[string]any `json:"cpus,omitempty"`
 	CPUInfo map[string]any   `json:"cpu_info,omitempty"`
 
+	// Hardware is the contents of "/sys/class/dmi/id", supplemented
+	// by the output of `dmidecode`.
+	Hardware map[string]string `json:"hardware,omitempty"`
+
+	// Kernel describes the running kernel and how it was booted.
+	Kernel map[string]any `json:"kernel,omitempty"`
+
 	// MachineID is the contents of "/etc/machine-id".
 	MachineID string `json:"machine_id,omitempty"`
 
 	// Memory is the contents of "/proc/meminfo".
 	Memory map[string]any `json:"memory,omitempty"`
 
+	// Mounts is the contents of "/proc/self/mountinfo", keyed by
+	// mount point, with capacities from `stat -f`.
+	Mounts map[string]map[string]any `json:"mounts,omitempty"`
+
 	// Interfaces is constructed from the output of `ip address`.
 	Interfaces map[string]map[string]any `json:"network_interfaces,omitempty"`
 
 	// OS is the contents of "/etc/os-release".
 	OS map[string]string `json:"operating_system,omitempty"`
+
+	// Meta describes how the HostInfo was gathered.
+	Meta *Meta `json:"meta,omitempty"`
+
+	// Extra holds sections populated by gatherers registered with
+	// [Register], keyed by whatever names those gatherers choose.
+	Extra map[string]any `json:"extra,omitempty"`
+
+	// Partial is non-nil if some gatherers failed, in which case it
+	// reports which, and why.  It is not serialized.
+	Partial *GatherError `json:"-"`
+}
+
+// merge copies everything gathered into o into r.
+func (r *HostInfo) merge(o *HostInfo) {
+	r.Disks = mergeNested(r.Disks, o.Disks)
+	if len(o.CPUs) > 0 {
+		r.CPUs = o.CPUs
+	}
+	r.CPUInfo = mergeMap(r.CPUInfo, o.CPUInfo)
+	r.Hardware = mergeMap(r.Hardware, o.Hardware)
+	r.Kernel = mergeMap(r.Kernel, o.Kernel)
+	if o.MachineID != "" {
+		r.MachineID = o.MachineID
+	}
+	r.Memory = mergeMap(r.Memory, o.Memory)
+	r.Mounts = mergeNested(r.Mounts, o.Mounts)
+	r.Interfaces = mergeNested(r.Interfaces, o.Interfaces)
+	r.OS = mergeMap(r.OS, o.OS)
+	r.Extra = mergeMap(r.Extra, o.Extra)
+}
+
+// mergeMap copies all key-value pairs from src into dst, allocating
+// dst if necessary, and returns dst.
+func mergeMap[Map ~map[K]V, K comparable, V any](dst, src Map) Map {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(Map, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// mergeNested is like mergeMap, but merges the values of keys that
+// exist in both dst and src rather than replacing them.
+func mergeNested[K1, K2 comparable, V any](
+	dst, src map[K1]map[K2]V,
+) map[K1]map[K2]V {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[K1]map[K2]V, len(src))
+	}
+	for k, v := range src {
+		dst[k] = mergeMap(dst[k], v)
+	}
+	return dst
 }
 
-// Gather returns a [HostInfo] describing a host.
-func Gather(ctx context.Context, invoker invoker.Invoker) (*HostInfo, error) {
-	result := &HostInfo{}
-	success := false
-
-	gi := gatherInvoker{ctx, invoker}
-	for _, op := range []gatherer{
-		gatherDiskAttrs,
-		gatherCPUInfo,
-		gatherMachineID,
-		gatherMemInfo,
-		gatherInterfaces,
-		gatherOSRelease,
-	} {
-		if err := op(&gi, result); err == nil {
-			success = true
-		} else {
+// Gather returns a [HostInfo] describing a host.  By default every
+// gatherer is run, several at once; use [Only] and [Skip] to select
+// a subset, [Concurrency] to limit the parallelism, and [Timeout] and
+// [GathererTimeout] to limit the time each gatherer may take.  Failed
+// gatherers are reported in the result's Partial field, unless every
+// gatherer failed, in which case the returned error is a [GatherError].
+func Gather(
+	ctx context.Context,
+	invoker invoker.Invoker,
+	opts ...Option,
+) (*HostInfo, error) {
+	return gather(ctx, invoker, newOptions(opts))
+}
+
+// gather implements [Gather].
+func gather(
+	ctx context.Context,
+	invoker invoker.Invoker,
+	o *options,
+) (*HostInfo, error) {
+	gatherers := registeredGatherers()
+	if err := o.validate(gatherers); err != nil {
+		return nil, err
+	}
+
+	var ops []Gatherer
+	for _, op := range gatherers {
+		if o.selects(op) {
+			ops = append(ops, op)
+		}
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("no gatherers selected")
+	}
+
+	// Each gatherer populates its own HostInfo, so gatherers running
+	// in parallel never write to the same maps.
+	infos := make([]HostInfo, len(ops))
+	errs := make([]error, len(ops))
+	metas := make([]*GathererMeta, len(ops))
+
+	start := time.Now()
+	gi := newGatherInvoker(ctx, invoker, o)
+	parallel(len(ops), o.concurrency, func(i int) {
+		op := ops[i]
+		gi := gi
+		gi.log = &gathererLog{}
+
+		start := time.Now()
+		errs[i] = runGatherer(gi, op, &infos[i], o.timeoutFor(op.Name()))
+		metas[i] = gi.log.meta(time.Since(start), errs[i])
+	})
+
+	meta := &Meta{
+		GatheredAt: start,
+		Duration:   time.Since(start),
+		Gatherers:  make(map[string]*GathererMeta, len(ops)),
+	}
+	meta.Tool, meta.Version = buildInfo()
+
+	result := &HostInfo{SchemaVersion: SchemaVersion, Meta: meta}
+	failed := &GatherError{}
+
+	for i, op := range ops {
+		meta.Gatherers[op.Name()] = metas[i]
+		if err := errs[i]; err != nil {
 			logger.Ctx(ctx).Warn().
-				Str("item", op.String()).
+				Str("item", op.Name()).
 				AnErr("reason", err).
 				Msg("Gather failed")
+			failed.add(op.Name(), err)
+			continue
 		}
+		result.merge(&infos[i])
+	}
+	result.linkMounts()
+
+	if len(failed.Errors) == len(ops) {
+		return nil, failed
+	} else if len(failed.Errors) > 0 {
+		result.Partial = failed
 	}
 
-	if !success {
-		return nil, errors.New("all gatherers failed")
+	if o.normalizeUnits {
+		result.NormalizeUnits()
 	}
 
 	return result, nil
 }
 
-// A gatherer is a function that populates part of a HostInfo.
-type gatherer func(*gatherInvoker, *HostInfo) error
+// parallel calls f(0) through f(n-1), with at most limit calls
+// running concurrently, and returns when every call has returned.
+func parallel(n, limit int, f func(int)) {
+	if limit < 1 {
+		limit = 1
+	}
+	sem := make(chan struct{}, limit)
 
-// String returns the name of a gatherer, for error messages etc.
-func (op gatherer) String() string {
-	name := runtime.FuncForPC(reflect.ValueOf(op).Pointer()).Name()
-	dot := strings.LastIndex(name, ".")
-	if dot > 0 {
-		name = name[dot+1:]
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem }()
+			defer wg.Done()
+			f(i)
+		}()
 	}
-	name, _ = strings.CutPrefix(name, "gather")
-	return name
+	wg.Wait()
 }
 
-// gatherInvoker modifies Invoker.
-type gatherInvoker struct {
+// runGatherer runs op with a context that expires after timeout, if
+// nonzero.  A gatherer that has not returned when its context expires
+// is abandoned: runGatherer returns the context's error immediately,
+// and r is left unmodified.
+func runGatherer(
+	gi GatherInvoker,
+	op Gatherer,
+	r *HostInfo,
+	timeout time.Duration,
+) error {
+	ctx := gi.context
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	gi.context = ctx
+
+	var scratch HostInfo
+	done := make(chan error, 1)
+	go func() {
+		done <- op.Gather(&gi, &scratch)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		*r = scratch
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// A GatherInvoker is passed to each [Gatherer], and provides access
+// to the host being gathered.
+type GatherInvoker struct {
 	context context.Context
 	invoker invoker.Invoker
+
+	// concurrency limits the number of commands a single gatherer
+	// may run in parallel.
+	concurrency int
+
+	// fsys, if non-nil, is used by ReadFile in preference to `cat`.
+	fsys fs.FS
+
+	// root is prepended to the names of files read by ReadFile.
+	root string
+
+	// log, if non-nil, records the commands run and files read.
+	log *gathererLog
 }
 
-// Logger returns the Logger associated with the gatherInvoker's
+// newGatherInvoker returns a GatherInvoker configured by o.  Unless
+// o specifies otherwise, files on local hosts are read directly, and
+// files on remote hosts are read using `cat`.
+func newGatherInvoker(
+	ctx context.Context,
+	inv invoker.Invoker,
+	o *options,
+) GatherInvoker {
+	gi := GatherInvoker{
+		context:     ctx,
+		invoker:     inv,
+		concurrency: o.concurrency,
+		fsys:        o.fsys,
+		root:        o.root,
+	}
+	if !o.fsysSet && isLocal(inv) {
+		gi.fsys = os.DirFS("/")
+	}
+	return gi
+}
+
+// isLocal reports whether inv runs commands on the local host.
+func isLocal(inv invoker.Invoker) bool {
+	v := reflect.ValueOf(inv)
+	return v.Comparable() && v.Equal(reflect.ValueOf(invoker.Exec))
+}
+
+// Context returns the context the gatherer is running in.  It is
+// canceled when the gatherer's time limit expires.
+func (gi *GatherInvoker) Context() context.Context {
+	return gi.context
+}
+
+// Root returns the directory that ReadFile reads files relative to.
+// It is empty unless [WithRoot] was specified.
+func (gi *GatherInvoker) Root() string {
+	return gi.root
+}
+
+// Logger returns the Logger associated with the GatherInvoker's
 // context, or an appropriate (non-nil) default if the invoker's
 // context has no logger associated.
-func (gi *gatherInvoker) Logger() *logger.Logger {
+func (gi *GatherInvoker) Logger() *logger.Logger {
 	return logger.Ctx(gi.context)
 }
 
 // Invoke wraps Invoker.Invoke.
-func (gi *gatherInvoker) Invoke(name string, arg ...string) (string, error) {
+func (gi *GatherInvoker) Invoke(name string, arg ...string) (string, error) {
 	ctx := gi.context
 	invoker := gi.invoker
 
@@ -106,6 +344,7 @@ func (gi *gatherInvoker) Invoke(name string, arg ...string) (string, error) {
 		Msg("Invoking")
 
 	out, err := invoker.Invoke(ctx, name, arg...)
+	gi.log.addCommand(append([]string{name}, arg...), err)
 	if err != nil {
 		return "", err
 	}
@@ -114,13 +353,13 @@ func (gi *gatherInvoker) Invoke(name string, arg ...string) (string, error) {
 }
 
 // InvokeSudo invokes the given command with sudo.
-func (gi *gatherInvoker) InvokeSudo(name string, arg ...string) (string, error) {
+func (gi *GatherInvoker) InvokeSudo(name string, arg ...string) (string, error) {
 	arg = append([]string{name}, arg...)
 	return gi.Invoke("sudo", arg...)
 }
 
 // InvokeRetrySudo retrys an invocation with sudo on error.
-func (gi *gatherInvoker) InvokeRetrySudo(name string, arg ...string) (string, error) {
+func (gi *GatherInvoker) InvokeRetrySudo(name string, arg ...string) (string, error) {
 	result, err1 := gi.Invoke(name, arg...)
 	if err1 == nil {
 		return result, nil
@@ -135,7 +374,63 @@ func (gi *gatherInvoker) InvokeRetrySudo(name string, arg ...string) (string, er
 	return "", errors.Join(err1, err2)
 }
 
-// ReadFile works like [os.ReadFile].
-func (gi *gatherInvoker) ReadFile(name string) (string, error) {
-	return gi.Invoke("cat", name)
+// ReadFile works like [os.ReadFile].  Files are read from the host's
+// filesystem if it is accessible, and using `cat` otherwise.  Names
+// should be absolute, and are taken relative to [GatherInvoker.Root]
+// if set.
+func (gi *GatherInvoker) ReadFile(name string) (string, error) {
+	if gi.root != "" {
+		name = path.Join(gi.root, name)
+	}
+
+	if gi.fsys == nil {
+		return gi.Invoke("cat", name)
+	}
+
+	gi.Logger().Debug().
+		Str("filename", name).
+		Msg("Reading")
+
+	b, err := fs.ReadFile(gi.fsys, strings.TrimPrefix(name, "/"))
+	gi.log.addFile(name, err)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// ReadDir returns the sorted names of the entries in the named
+// directory.  Like ReadFile, directories are read from the host's
+// filesystem if it is accessible, and using `ls` otherwise.
+func (gi *GatherInvoker) ReadDir(name string) ([]string, error) {
+	if gi.root != "" {
+		name = path.Join(gi.root, name)
+	}
+
+	if gi.fsys == nil {
+		s, err := gi.Invoke("ls", "-1A", "--", name)
+		if err != nil {
+			return nil, err
+		}
+		names := strings.Fields(s)
+		slices.Sort(names)
+		return names, nil
+	}
+
+	gi.Logger().Debug().
+		Str("dirname", name).
+		Msg("Reading")
+
+	entries, err := fs.ReadDir(gi.fsys, strings.TrimPrefix(name, "/"))
+	gi.log.addFile(name, err)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
 }
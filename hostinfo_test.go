@@ -3,8 +3,15 @@ package hostinfo
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"gbenson.net/go/invoker"
 	"gbenson.net/go/logger"
@@ -25,7 +32,7 @@ func exec(t *testing.T, g gatherer) (HostInfo, error) {
 // invoke runs the specified gatherer with the specified [invoker.Invoker].
 func invoke(t *testing.T, i invoker.Invoker, g gatherer) (result HostInfo, err error) {
 	t.Helper()
-	err = g(&gatherInvoker{testctx(t), i}, &result)
+	err = g(&GatherInvoker{context: testctx(t), invoker: i}, &result)
 	return
 }
 
@@ -71,3 +78,338 @@ func TestGather(t *testing.T) {
 	assert.NilError(t, os.WriteFile(filename, data, 0666))
 	logger.Ctx(ctx).Debug().Str("filename", filename).Msg("Wrote")
 }
+
+func TestGather_only(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(testMemInfo, nil)
+	mock.ExpectInvoke("cat", "/etc/os-release").Returns(ubuntuOSRelease, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("MemInfo"),
+		Only("OSRelease"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, len(r.Memory), 55)
+	assert.Equal(t, r.OS["id"], "ubuntu")
+	assert.Equal(t, len(r.CPUs), 0)
+}
+
+func TestGather_skip(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/cpuinfo").Returns(armCPUInfo, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("CPUInfo", "MemInfo"),
+		Skip("MemInfo"))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, len(r.CPUs), 4)
+	assert.Equal(t, len(r.Memory), 0)
+}
+
+func TestGather_unknown(t *testing.T) {
+	mock := invoker.NewMock(t)
+
+	r, err := Gather(testctx(t), mock, Skip("DiskAttr"))
+	assert.Check(t, r == nil)
+	assert.Error(t, err, `"DiskAttr": unknown gatherer`)
+
+	var uge *UnknownGathererError
+	assert.Check(t, errors.As(err, &uge))
+	assert.Equal(t, uge.Name, "DiskAttr")
+}
+
+func TestGather_none(t *testing.T) {
+	mock := invoker.NewMock(t)
+
+	r, err := Gather(testctx(t), mock, Only("CPUInfo"), Skip("CPUInfo"))
+	assert.Check(t, r == nil)
+	assert.Error(t, err, "no gatherers selected")
+}
+
+func TestGather_partial(t *testing.T) {
+	want := &InvalidLineError{"os_test", "x"}
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(testMemInfo, nil)
+	mock.ExpectInvoke("cat", "/etc/os-release").Returns(nil, want)
+
+	r, err := Gather(testctx(t), mock,
+		Only("MemInfo", "OSRelease"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, len(r.Memory), 55)
+
+	assert.Assert(t, r.Partial != nil)
+	assert.DeepEqual(t, r.Partial.Errors, map[string]error{"OSRelease": want})
+	assert.Check(t, errors.Is(r.Partial, want))
+
+	var ile *InvalidLineError
+	assert.Check(t, errors.As(r.Partial, &ile))
+	assert.Equal(t, ile.Prefix, "os_test")
+}
+
+func TestGather_failed(t *testing.T) {
+	err1 := errors.New("no such file")
+	err2 := &InvalidLineError{"os_test", "x"}
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(nil, err1)
+	mock.ExpectInvoke("cat", "/etc/os-release").Returns(nil, err2)
+
+	r, err := Gather(testctx(t), mock,
+		Only("MemInfo", "OSRelease"),
+		Concurrency(1))
+	assert.Check(t, r == nil)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Error(t, err,
+		`MemInfo: no such file; OSRelease: os_test: "x": invalid line`)
+
+	var ge *GatherError
+	assert.Assert(t, errors.As(err, &ge))
+	assert.Equal(t, len(ge.Errors), 2)
+	assert.Check(t, errors.Is(err, err1))
+	assert.Check(t, errors.Is(err, err2))
+}
+
+// cannedInvoker returns canned outputs, recording the maximum number
+// of invocations that were in flight at once.  Outputs may be changed
+// while invocations are in flight using set.
+type cannedInvoker struct {
+	mu          sync.Mutex
+	outputs     map[string][]byte
+	inFlight    int
+	maxInFlight int
+}
+
+func (ci *cannedInvoker) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	ci.mu.Lock()
+	ci.inFlight++
+	ci.maxInFlight = max(ci.maxInFlight, ci.inFlight)
+	ci.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	ci.mu.Lock()
+	ci.inFlight--
+	ci.mu.Unlock()
+
+	command := strings.Join(append([]string{name}, arg...), " ")
+	ci.mu.Lock()
+	out, found := ci.outputs[command]
+	ci.mu.Unlock()
+	if found {
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s: unexpected command", command)
+}
+
+// set sets the output of command, which then fails if output is nil.
+func (ci *cannedInvoker) set(command string, output []byte) {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	if output == nil {
+		delete(ci.outputs, command)
+		return
+	}
+	if ci.outputs == nil {
+		ci.outputs = make(map[string][]byte)
+	}
+	ci.outputs[command] = output
+}
+
+func TestGather_concurrency(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		ci := &cannedInvoker{outputs: map[string][]byte{
+			"cat /proc/cpuinfo":   x86CPUInfo,
+			"cat /proc/meminfo":   testMemInfo,
+			"cat /etc/os-release": fedoraOSRelease,
+		}}
+
+		r, err := Gather(testctx(t), ci,
+			Only("CPUInfo", "MemInfo", "OSRelease"),
+			Concurrency(limit))
+		assert.NilError(t, err)
+		assert.Check(t, r.Partial == nil)
+		assert.Equal(t, ci.maxInFlight, limit)
+
+		assert.Equal(t, len(r.CPUs), 12)
+		assert.Equal(t, len(r.Memory), 55)
+		assert.Equal(t, r.OS["id"], "fedora")
+	}
+}
+
+// wedgedInvoker wraps an invoker, causing invocations of the given
+// commands to block, ignoring their contexts, until the test ends.
+type wedgedInvoker struct {
+	invoker.Invoker
+	wedged  map[string]bool
+	release chan struct{}
+}
+
+func newWedgedInvoker(
+	t *testing.T,
+	i invoker.Invoker,
+	commands ...string,
+) invoker.Invoker {
+	wi := &wedgedInvoker{
+		Invoker: i,
+		wedged:  make(map[string]bool),
+		release: make(chan struct{}),
+	}
+	for _, command := range commands {
+		wi.wedged[command] = true
+	}
+	t.Cleanup(func() { close(wi.release) })
+	return wi
+}
+
+func (wi *wedgedInvoker) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	command := strings.Join(append([]string{name}, arg...), " ")
+	if wi.wedged[command] {
+		<-wi.release
+		return nil, errors.New("released")
+	}
+	return wi.Invoker.Invoke(ctx, name, arg...)
+}
+
+func TestGather_timeout(t *testing.T) {
+	ci := &cannedInvoker{outputs: map[string][]byte{
+		"cat /etc/os-release": fedoraOSRelease,
+	}}
+	wi := newWedgedInvoker(t, ci, "cat /proc/meminfo", "cat /proc/cpuinfo")
+
+	start := time.Now()
+	r, err := Gather(testctx(t), wi,
+		Only("CPUInfo", "MemInfo", "OSRelease"),
+		Timeout(50*time.Millisecond),
+		GathererTimeout("OSRelease", 0),
+		GathererTimeout("CPUInfo", 10*time.Millisecond))
+	assert.NilError(t, err)
+	assert.Check(t, time.Since(start) < time.Second)
+
+	assert.Equal(t, r.OS["id"], "fedora")
+	assert.Equal(t, len(r.Memory), 0)
+	assert.Equal(t, len(r.CPUs), 0)
+
+	assert.Assert(t, r.Partial != nil)
+	assert.Equal(t, len(r.Partial.Errors), 2)
+	assert.Check(t, errors.Is(r.Partial.Errors["CPUInfo"], context.DeadlineExceeded))
+	assert.Check(t, errors.Is(r.Partial.Errors["MemInfo"], context.DeadlineExceeded))
+}
+
+func TestGather_unknownTimeout(t *testing.T) {
+	_, err := Gather(testctx(t), invoker.NewMock(t),
+		GathererTimeout("Disks", time.Second))
+	assert.Error(t, err, `"Disks": unknown gatherer`)
+}
+
+func TestGather_withFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"proc/meminfo":   {Data: testMemInfo},
+		"etc/os-release": {Data: ubuntuOSRelease},
+	}
+	mock := invoker.NewMock(t)
+
+	r, err := Gather(testctx(t), mock,
+		Only("MemInfo", "OSRelease", "MachineID"),
+		WithFS(fsys))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, len(r.Memory), 55)
+	assert.Equal(t, r.OS["id"], "ubuntu")
+
+	assert.Assert(t, r.Partial != nil)
+	assert.Check(t, errors.Is(r.Partial.Errors["MachineID"], fs.ErrNotExist))
+}
+
+func TestGather_withNilFS(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/etc/os-release").Returns(fedoraOSRelease, nil)
+
+	r, err := Gather(testctx(t), mock, Only("OSRelease"), WithFS(nil))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, r.OS["id"], "fedora")
+}
+
+func TestNewGatherInvoker(t *testing.T) {
+	ctx := testctx(t)
+
+	gi := newGatherInvoker(ctx, invoker.Exec, newOptions(nil))
+	assert.Check(t, gi.fsys != nil)
+
+	gi = newGatherInvoker(ctx, invoker.Exec, newOptions([]Option{WithFS(nil)}))
+	assert.Check(t, gi.fsys == nil)
+
+	gi = newGatherInvoker(ctx, invoker.NewMock(t), newOptions(nil))
+	assert.Check(t, gi.fsys == nil)
+}
+
+func TestGather_withRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/os-release":           {Data: ubuntuOSRelease},
+		"mnt/image/etc/os-release": {Data: fedoraOSRelease},
+		"mnt/image/etc/machine-id": {
+			Data: []byte("0123456789abcdef0123456789abcdef\n"),
+		},
+	}
+	mock := invoker.NewMock(t)
+
+	r, err := Gather(testctx(t), mock, WithRoot("/mnt/image"), WithFS(fsys))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Check(t, r.Partial == nil)
+	assert.Equal(t, r.OS["id"], "fedora")
+	assert.Equal(t, r.MachineID, "0123456789abcdef0123456789abcdef")
+	assert.Equal(t, len(r.CPUs), 0)
+	assert.Equal(t, len(r.Interfaces), 0)
+}
+
+func TestGather_withRootRemote(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/srv/rootfs/etc/os-release").
+		Returns(ubuntuOSRelease, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("CPUInfo", "OSRelease"),
+		WithRoot("/srv/rootfs"))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, r.OS["id"], "ubuntu")
+	assert.Equal(t, len(r.CPUs), 0)
+}
+
+func TestGather_withRootNone(t *testing.T) {
+	_, err := Gather(testctx(t), invoker.NewMock(t),
+		Only("CPUInfo", "Interfaces"),
+		WithRoot("/mnt"))
+	assert.Error(t, err, "no gatherers selected")
+}
+
+// Relative roots would be resolved against "/" rather than the
+// working directory, so they are rejected.
+func TestGather_withRootRelative(t *testing.T) {
+	_, err := Gather(testctx(t), invoker.NewMock(t),
+		Only("OSRelease"),
+		WithRoot("./image"))
+	assert.Error(t, err, `root "./image" is not an absolute path`)
+}
+
+// assertJSONEqual fails a test if got and want serialize differently.
+func assertJSONEqual(t *testing.T, got, want any) {
+	t.Helper()
+	gotJSON, err := json.Marshal(got)
+	assert.NilError(t, err)
+	wantJSON, err := json.Marshal(want)
+	assert.NilError(t, err)
+	assert.Equal(t, string(gotJSON), string(wantJSON))
+}
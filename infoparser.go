@@ -22,6 +22,15 @@ var strtobool = map[string]bool{
 	"yes":   true,
 }
 
+// parsedUnits are the units ParseLine recognizes in dimensioned
+// values.  See [HostInfo.NormalizeUnits] for their meanings.
+var parsedUnits = map[string]bool{
+	"kb":    true,
+	"mb":    true,
+	"gb":    true,
+	"pages": true,
+}
+
 var floatyIntegerRx = regexp.MustCompile(`^(\d+)\.0+$`)
 
 // ParseLine splits a colon-separated line from /proc/cpuinfo or
@@ -29,9 +38,9 @@ var floatyIntegerRx = regexp.MustCompile(`^(\d+)\.0+$`)
 // converted to snake case as necessary; the returned value will be
 // converted to type "int" or "bool" if possible, returned as type
 // "string otherwise.  Values comprising an integer followed by
-// whitespace and a string are interpreted as dimensioned values and
-// returned as type "int", with the string part (the unit) appended
-// to the returned key.
+// whitespace and one of the units in parsedUnits are interpreted as
+// dimensioned values and returned as type "int", with the unit
+// appended to the returned key.
 func (p *kvpp) ParseLine(line string) (key string, value any, err error) {
 	key, v, found := strings.Cut(line, ":")
 	if !found {
@@ -62,8 +71,7 @@ func (p *kvpp) ParseLine(line string) (key string, value any, err error) {
 		return key, v, nil
 	}
 	unit := strings.ToLower(fields[1])
-	if unit != "kb" {
-		// XXX allow others?
+	if !parsedUnits[unit] {
 		return key, v, nil
 	}
 	n, err := strconv.Atoi(fields[0])
@@ -10,7 +10,7 @@ import (
 )
 
 // gatherInterfaces gathers the content of `ip address`.
-func gatherInterfaces(gi *gatherInvoker, r *HostInfo) error {
+func gatherInterfaces(gi *GatherInvoker, r *HostInfo) error {
 	var devices []map[string]any
 
 	if s, err1 := gi.Invoke("ip", "--json", "address", "show"); err1 == nil {
@@ -0,0 +1,149 @@
+package hostinfo
+
+import (
+	"strconv"
+	"strings"
+)
+
+// secureBootVar is the efivarfs file holding the Secure Boot state.
+const secureBootVar = "/sys/firmware/efi/efivars/" +
+	"SecureBoot-8be4df61-93ca-11d2-aa0d-e098032b8c8c"
+
+// gatherKernel gathers information about the running kernel and how
+// it was booted, from `uname`, `/proc` and `/sys/firmware/efi`.
+func gatherKernel(gi *GatherInvoker, r *HostInfo) error {
+	release, err := gi.Invoke("uname", "-r")
+	if err != nil {
+		return err
+	}
+	k := map[string]any{"release": strings.TrimSpace(release)}
+
+	optional := func(key string, value any, err error) {
+		if err != nil {
+			gi.Logger().Debug().
+				Str("key", key).
+				AnErr("reason", err).
+				Msg("Skipping")
+			return
+		}
+		k[key] = value
+	}
+	trimmed := func(s string, err error) (string, error) {
+		return strings.TrimSpace(s), err
+	}
+
+	s, err := trimmed(gi.Invoke("uname", "-v"))
+	optional("version", s, err)
+
+	s, err = trimmed(gi.Invoke("uname", "-m"))
+	optional("machine", s, err)
+
+	s, err = trimmed(gi.ReadFile("/proc/sys/kernel/osrelease"))
+	optional("osrelease", s, err)
+
+	if s, err = trimmed(gi.ReadFile("/proc/cmdline")); err == nil {
+		k["cmdline"] = s
+		k["cmdline_args"] = parseKernelCmdline(s)
+	} else {
+		optional("cmdline", nil, err)
+	}
+
+	s, err = trimmed(gi.ReadFile("/proc/sys/kernel/random/boot_id"))
+	optional("boot_id", s, err)
+
+	uptime, err := readUptime(gi)
+	optional("uptime_seconds", uptime, err)
+
+	// fw_platform_size exists on all EFI systems.
+	s, err = trimmed(gi.ReadFile("/sys/firmware/efi/fw_platform_size"))
+	k["efi"] = err == nil
+	if err == nil {
+		if n, err := strconv.Atoi(s); err == nil {
+			k["efi_platform_size"] = n
+		}
+		secureBoot, err := readSecureBoot(gi)
+		optional("secure_boot", secureBoot, err)
+	}
+
+	r.Kernel = k
+	return nil
+}
+
+// readUptime returns the number of seconds since the host booted.
+func readUptime(gi *GatherInvoker) (float64, error) {
+	s, err := gi.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	uptime, _, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return strconv.ParseFloat(uptime, 64)
+}
+
+// readSecureBoot returns whether Secure Boot is enabled, according
+// to efivarfs.
+func readSecureBoot(gi *GatherInvoker) (bool, error) {
+	s, err := gi.ReadFile(secureBootVar)
+	if err != nil {
+		return false, err
+	}
+	// The first 4 bytes are the variable's attributes.
+	if len(s) != 5 {
+		return false, secureBootError(s)
+	}
+	return s[4] == 1, nil
+}
+
+func secureBootError(s string) error {
+	return &InvalidLineError{"SecureBoot", s}
+}
+
+// parseKernelCmdline parses a kernel command line into a mapping of
+// parameters to values.  Parameters without values map to true, and
+// parameters that are specified more than once map to lists of their
+// values.  Double quotes, which protect spaces, are removed.
+func parseKernelCmdline(s string) map[string]any {
+	args := make(map[string]any)
+	for _, arg := range splitKernelCmdline(s) {
+		arg = strings.ReplaceAll(arg, `"`, "")
+		key, value, found := strings.Cut(arg, "=")
+		var v any = true
+		if found {
+			v = value
+		}
+
+		switch prev := args[key].(type) {
+		case nil:
+			args[key] = v
+		case []any:
+			args[key] = append(prev, v)
+		default:
+			args[key] = []any{prev, v}
+		}
+	}
+	return args
+}
+
+// splitKernelCmdline splits a kernel command line into parameters.
+// Like the kernel, it splits at spaces outside double quotes.
+func splitKernelCmdline(s string) []string {
+	var args []string
+	var arg strings.Builder
+	inQuotes := false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (c == ' ' || c == '\t' || c == '\n'):
+			if arg.Len() > 0 {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+			continue
+		}
+		arg.WriteRune(c)
+	}
+	if arg.Len() > 0 {
+		args = append(args, arg.String())
+	}
+	return args
+}
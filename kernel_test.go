@@ -0,0 +1,129 @@
+package hostinfo
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGatherKernel_live(t *testing.T) {
+	r := assertExec(t, gatherKernel)
+	assert.Check(t, r.Kernel["release"] != "")
+	assert.Equal(t, r.Kernel["release"], r.Kernel["osrelease"])
+	_, found := r.Kernel["efi"]
+	assert.Check(t, found)
+}
+
+const testCmdline = `BOOT_IMAGE=/vmlinuz-6.8.0-45-generic ` +
+	`root=/dev/mapper/vgubuntu-root ro quiet splash ` +
+	`console=tty0 console=ttyS0,115200n8 ` +
+	`dyndbg="file drivers/usb/* +p" vt.handoff=7` + "\n"
+
+func TestGatherKernel_mock(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("uname", "-r").
+		Returns([]byte("6.8.0-45-generic\n"), nil)
+	mock.ExpectInvoke("uname", "-v").
+		Returns([]byte("#45-Ubuntu SMP PREEMPT_DYNAMIC Fri Aug 30 12:02:04 UTC 2024\n"), nil)
+	mock.ExpectInvoke("uname", "-m").
+		Returns([]byte("x86_64\n"), nil)
+	mock.ExpectInvoke("cat", "/proc/sys/kernel/osrelease").
+		Returns([]byte("6.8.0-45-generic\n"), nil)
+	mock.ExpectInvoke("cat", "/proc/cmdline").
+		Returns([]byte(testCmdline), nil)
+	mock.ExpectInvoke("cat", "/proc/sys/kernel/random/boot_id").
+		Returns([]byte("0b1d0cf3-3c2e-4b8c-9a6f-4f1a2ad7a7e3\n"), nil)
+	mock.ExpectInvoke("cat", "/proc/uptime").
+		Returns([]byte("35183.62 269744.13\n"), nil)
+	mock.ExpectInvoke("cat", "/sys/firmware/efi/fw_platform_size").
+		Returns([]byte("64\n"), nil)
+	mock.ExpectInvoke("cat", secureBootVar).
+		Returns([]byte{0x06, 0x00, 0x00, 0x00, 0x01}, nil)
+
+	r := assertMock(t, gatherKernel, mock)
+	assert.DeepEqual(t, r.Kernel, map[string]any{
+		"release":   "6.8.0-45-generic",
+		"version":   "#45-Ubuntu SMP PREEMPT_DYNAMIC Fri Aug 30 12:02:04 UTC 2024",
+		"machine":   "x86_64",
+		"osrelease": "6.8.0-45-generic",
+		"cmdline":   testCmdline[:len(testCmdline)-1],
+		"cmdline_args": map[string]any{
+			"BOOT_IMAGE": "/vmlinuz-6.8.0-45-generic",
+			"root":       "/dev/mapper/vgubuntu-root",
+			"ro":         true,
+			"quiet":      true,
+			"splash":     true,
+			"console":    []any{"tty0", "ttyS0,115200n8"},
+			"dyndbg":     "file drivers/usb/* +p",
+			"vt.handoff": "7",
+		},
+		"boot_id":           "0b1d0cf3-3c2e-4b8c-9a6f-4f1a2ad7a7e3",
+		"uptime_seconds":    35183.62,
+		"efi":               true,
+		"efi_platform_size": 64,
+		"secure_boot":       true,
+	})
+}
+
+func TestGatherKernel_noEFI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"proc/uptime": {Data: []byte("12.5 40.1\n")},
+	}
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("uname", "-r").Returns([]byte("6.1.0\n"), nil)
+	mock.ExpectInvoke("uname", "-v").Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("uname", "-m").Returns([]byte("aarch64\n"), nil)
+
+	gi := &GatherInvoker{context: testctx(t), invoker: mock, fsys: fsys}
+	var r HostInfo
+	assert.NilError(t, gatherKernel(gi, &r))
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.DeepEqual(t, r.Kernel, map[string]any{
+		"release":        "6.1.0",
+		"machine":        "aarch64",
+		"uptime_seconds": 12.5,
+		"efi":            false,
+	})
+}
+
+func TestGatherKernel_noUname(t *testing.T) {
+	want := errors.New("exit status 127")
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("uname", "-r").Returns(nil, want)
+
+	_, err := invoke(t, mock, gatherKernel)
+	assert.Check(t, errors.Is(err, want))
+}
+
+func TestParseKernelCmdline(t *testing.T) {
+	assert.DeepEqual(t, parseKernelCmdline(""), map[string]any{})
+	assert.DeepEqual(t, parseKernelCmdline(`a b=1  b=2 b=3 "c=x y"`),
+		map[string]any{
+			"a": true,
+			"b": []any{"1", "2", "3"},
+			"c": "x y",
+		})
+}
+
+func TestReadSecureBoot(t *testing.T) {
+	for _, tc := range []struct {
+		data    string
+		want    bool
+		wantErr bool
+	}{
+		{"\x06\x00\x00\x00\x01", true, false},
+		{"\x06\x00\x00\x00\x00", false, false},
+		{"\x06\x00", false, true},
+	} {
+		fsys := fstest.MapFS{
+			secureBootVar[1:]: {Data: []byte(tc.data)},
+		}
+		gi := &GatherInvoker{context: testctx(t), fsys: fsys}
+		got, err := readSecureBoot(gi)
+		assert.Equal(t, got, tc.want)
+		assert.Equal(t, err != nil, tc.wantErr)
+	}
+}
@@ -16,7 +16,7 @@ var machineIDrx = regexp.MustCompile(`^[0-9a-f]{32}$`)
 // a 16-byte/128-bit value. This ID may not be all zeros." [1]
 //
 // [1]: https://www.man7.org/linux/man-pages/man5/machine-id.5.html
-func gatherMachineID(gi *gatherInvoker, r *HostInfo) error {
+func gatherMachineID(gi *GatherInvoker, r *HostInfo) error {
 	s, err := gi.ReadFile("/etc/machine-id")
 	if err != nil {
 		return err
@@ -6,7 +6,7 @@ import (
 )
 
 // gatherMemInfo gathers the content of `/proc/meminfo`.
-func gatherMemInfo(gi *gatherInvoker, r *HostInfo) error {
+func gatherMemInfo(gi *GatherInvoker, r *HostInfo) error {
 	s, err := gi.ReadFile("/proc/meminfo")
 	if err != nil {
 		return err
@@ -0,0 +1,158 @@
+package hostinfo
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Meta describes how a [HostInfo] was gathered.
+type Meta struct {
+	// GatheredAt is when gathering started.
+	GatheredAt time.Time `json:"gathered_at"`
+
+	// Duration is how long gathering took.
+	Duration time.Duration `json:"duration_ns"`
+
+	// Tool is the path of the program that gathered the HostInfo,
+	// and Version is the version of this package it was built with,
+	// as reported by [debug.ReadBuildInfo].
+	Tool    string `json:"tool,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Gatherers describes each gatherer that was run, by name.
+	Gatherers map[string]*GathererMeta `json:"gatherers"`
+}
+
+// Outcomes of running a gatherer.
+const (
+	OutcomeOK       = "ok"
+	OutcomeFailed   = "failed"
+	OutcomeTimedOut = "timed_out"
+)
+
+// A GathererMeta describes how a single gatherer ran.
+type GathererMeta struct {
+	// Duration is how long the gatherer ran for.
+	Duration time.Duration `json:"duration_ns"`
+
+	// Outcome is one of [OutcomeOK], [OutcomeFailed] or
+	// [OutcomeTimedOut].  Error is the error the gatherer failed
+	// with, if any.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+
+	// Commands are the commands the gatherer ran, in order, and
+	// Files are the files it read directly.
+	Commands []*CommandMeta `json:"commands,omitempty"`
+	Files    []*FileMeta    `json:"files,omitempty"`
+}
+
+// A CommandMeta describes a single command run by a gatherer.
+// Failed commands are included, so fallbacks can be identified.
+type CommandMeta struct {
+	// Command is the command and its arguments.
+	Command []string `json:"command"`
+
+	// Error is the error the command failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// A FileMeta describes a single file read directly by a gatherer.
+// Failed reads are included, as for [CommandMeta].
+type FileMeta struct {
+	// Path is the name of the file, including any root directory.
+	Path string `json:"path"`
+
+	// Error is the error the read failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// gathererLog records the commands run and files read by a gatherer.
+type gathererLog struct {
+	mu       sync.Mutex
+	commands []*CommandMeta
+	files    []*FileMeta
+}
+
+func (l *gathererLog) addCommand(command []string, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commands = append(l.commands, newCommandMeta(command, err))
+}
+
+func (l *gathererLog) addFile(name string, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fm := &FileMeta{Path: name}
+	if err != nil {
+		fm.Error = err.Error()
+	}
+	l.files = append(l.files, fm)
+}
+
+func newCommandMeta(command []string, err error) *CommandMeta {
+	cm := &CommandMeta{Command: slices.Clone(command)}
+	if err != nil {
+		cm.Error = err.Error()
+	}
+	return cm
+}
+
+// meta returns a GathererMeta describing a gatherer that ran for d
+// and returned err.
+func (l *gathererLog) meta(d time.Duration, err error) *GathererMeta {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	gm := &GathererMeta{
+		Duration: d,
+		Outcome:  OutcomeOK,
+		Commands: slices.Clone(l.commands),
+		Files:    slices.Clone(l.files),
+	}
+	if err != nil {
+		gm.Outcome = OutcomeFailed
+		if errors.Is(err, context.DeadlineExceeded) {
+			gm.Outcome = OutcomeTimedOut
+		}
+		gm.Error = err.Error()
+	}
+	return gm
+}
+
+// modulePath is the path of this package's module.
+const modulePath = "gbenson.net/go/hostinfo"
+
+// buildInfo returns the path of the running program and the version
+// of this package it was built with.
+func buildInfo() (tool, version string) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+	tool = bi.Path
+
+	if bi.Main.Path == modulePath {
+		return tool, bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		return tool, dep.Version
+	}
+	return tool, ""
+}
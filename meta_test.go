@@ -0,0 +1,109 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGather_meta(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(testMemInfo, nil)
+	mock.ExpectInvoke("ip", "--json", "address", "show").
+		Returns(nil, errors.New("exit status 255"))
+	mock.ExpectInvoke("ip", "address", "show").
+		Returns(testInterfacesNoJSON, nil)
+	mock.ExpectInvoke("cat", "/etc/os-release").
+		Returns(nil, errors.New("exit status 1"))
+
+	start := time.Now()
+	r, err := Gather(testctx(t), mock,
+		Only("MemInfo", "Interfaces", "OSRelease"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	m := r.Meta
+	assert.Assert(t, m != nil)
+	assert.Check(t, !m.GatheredAt.Before(start))
+	assert.Check(t, m.Duration > 0)
+	assert.Equal(t, len(m.Gatherers), 3)
+
+	assert.DeepEqual(t, m.Gatherers["MemInfo"].Commands, []*CommandMeta{
+		{Command: []string{"cat", "/proc/meminfo"}},
+	})
+	assert.Equal(t, m.Gatherers["MemInfo"].Outcome, OutcomeOK)
+	assert.Equal(t, m.Gatherers["MemInfo"].Error, "")
+
+	assert.DeepEqual(t, m.Gatherers["Interfaces"].Commands, []*CommandMeta{
+		{
+			Command: []string{"ip", "--json", "address", "show"},
+			Error:   "exit status 255",
+		},
+		{Command: []string{"ip", "address", "show"}},
+	})
+	assert.Equal(t, m.Gatherers["Interfaces"].Outcome, OutcomeOK)
+
+	assert.Equal(t, m.Gatherers["OSRelease"].Outcome, OutcomeFailed)
+	assert.Equal(t, m.Gatherers["OSRelease"].Error, "exit status 1")
+}
+
+func TestGather_metaFiles(t *testing.T) {
+	fsys := fstest.MapFS{"proc/meminfo": {Data: testMemInfo}}
+
+	r, err := Gather(testctx(t), invoker.NewMock(t),
+		Only("MemInfo", "MachineID"),
+		WithFS(fsys))
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, r.Meta.Gatherers["MemInfo"].Files, []*FileMeta{
+		{Path: "/proc/meminfo"},
+	})
+	assert.Equal(t, len(r.Meta.Gatherers["MemInfo"].Commands), 0)
+
+	files := r.Meta.Gatherers["MachineID"].Files
+	assert.Equal(t, len(files), 1)
+	assert.Check(t, files[0].Error != "")
+
+	b, err := json.Marshal(r.Meta.Gatherers["MemInfo"])
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(string(b),
+		`"files":[{"path":"/proc/meminfo"}]`), string(b))
+	b, err = json.Marshal(r)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(b))
+}
+
+func TestGather_metaTimeout(t *testing.T) {
+	ci := &cannedInvoker{outputs: map[string][]byte{
+		"cat /etc/os-release": fedoraOSRelease,
+	}}
+	wi := newWedgedInvoker(t, ci, "cat /proc/meminfo")
+
+	r, err := Gather(testctx(t), wi,
+		Only("MemInfo", "OSRelease"),
+		Timeout(10*time.Millisecond),
+		GathererTimeout("OSRelease", 0))
+	assert.NilError(t, err)
+
+	assert.Equal(t, r.Meta.Gatherers["MemInfo"].Outcome, OutcomeTimedOut)
+	assert.Equal(t, r.Meta.Gatherers["OSRelease"].Outcome, OutcomeOK)
+}
+
+func TestGather_metaValidates(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(testMemInfo, nil)
+
+	r, err := Gather(testctx(t), mock, Only("MemInfo"))
+	assert.NilError(t, err)
+
+	b, err := json.Marshal(r)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(b))
+}
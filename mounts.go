@@ -0,0 +1,215 @@
+package hostinfo
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+// pseudoFilesystems are filesystem types that gatherMounts does not
+// report the capacity of.
+var pseudoFilesystems = map[string]bool{
+	"autofs":      true,
+	"binfmt_misc": true,
+	"bpf":         true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"configfs":    true,
+	"debugfs":     true,
+	"devpts":      true,
+	"efivarfs":    true,
+	"fusectl":     true,
+	"hugetlbfs":   true,
+	"mqueue":      true,
+	"nsfs":        true,
+	"proc":        true,
+	"pstore":      true,
+	"rpc_pipefs":  true,
+	"securityfs":  true,
+	"selinuxfs":   true,
+	"sysfs":       true,
+	"tracefs":     true,
+}
+
+// gatherMounts gathers the content of `/proc/self/mountinfo`, keyed
+// by mount point, with the capacity of each filesystem from `stat -f`.
+// Where mounts are stacked, only the topmost mount, which is the one
+// that is visible, is reported.
+func gatherMounts(gi *GatherInvoker, r *HostInfo) error {
+	s, err := gi.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return err
+	}
+
+	mounts := make(map[string]map[string]any)
+	var mountPoints []string
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		mount, err := parseMountInfo(line)
+		if err != nil {
+			return err
+		}
+
+		mountPoint := mount["mount_point"].(string)
+		if _, found := mounts[mountPoint]; !found {
+			mountPoints = append(mountPoints, mountPoint)
+		}
+		mounts[mountPoint] = mount
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var statted []string
+	for _, mountPoint := range mountPoints {
+		if !pseudoFilesystems[mounts[mountPoint]["fstype"].(string)] {
+			statted = append(statted, mountPoint)
+		}
+	}
+
+	results := make([]map[string]any, len(statted))
+	errs := make([]error, len(statted))
+	parallel(len(statted), gi.concurrency, func(i int) {
+		results[i], errs[i] = gatherStatFS(gi, statted[i])
+	})
+
+	for i, mountPoint := range statted {
+		if err := errs[i]; err != nil {
+			gi.Logger().Warn().
+				Str("item", "StatFS").
+				Str("mount_point", mountPoint).
+				AnErr("reason", err).
+				Msg("Gather failed")
+			continue
+		}
+		mergeMap(mounts[mountPoint], results[i])
+	}
+
+	r.Mounts = mounts
+	return nil
+}
+
+func mountInfoError(line string) error {
+	return &InvalidLineError{"mountinfo", line}
+}
+
+// parseMountInfo parses one line of `/proc/self/mountinfo`.  See
+// proc_pid_mountinfo(5) for the format.
+func parseMountInfo(line string) (map[string]any, error) {
+	fields := strings.Fields(line)
+	sep := -1
+	for i, field := range fields {
+		if i >= 6 && field == "-" {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 || len(fields) < sep+4 {
+		return nil, mountInfoError(line)
+	}
+
+	mountID, err1 := strconv.Atoi(fields[0])
+	parentID, err2 := strconv.Atoi(fields[1])
+	major, minor, found := strings.Cut(fields[2], ":")
+	if err1 != nil || err2 != nil || !found {
+		return nil, mountInfoError(line)
+	}
+	majorNum, err1 := strconv.Atoi(major)
+	minorNum, err2 := strconv.Atoi(minor)
+	if err1 != nil || err2 != nil {
+		return nil, mountInfoError(line)
+	}
+
+	mount := map[string]any{
+		"mount_id":      mountID,
+		"parent_id":     parentID,
+		"major":         majorNum,
+		"minor":         minorNum,
+		"root":          unescapeMountInfo(fields[3]),
+		"mount_point":   unescapeMountInfo(fields[4]),
+		"options":       strings.Split(fields[5], ","),
+		"fstype":        unescapeMountInfo(fields[sep+1]),
+		"source":        unescapeMountInfo(fields[sep+2]),
+		"super_options": strings.Split(fields[sep+3], ","),
+	}
+
+	propagation := fields[6:sep]
+	if len(propagation) == 0 {
+		propagation = []string{"private"}
+	}
+	mount["propagation"] = propagation
+
+	return mount, nil
+}
+
+// unescapeMountInfo reverses the octal escaping of spaces, tabs,
+// newlines and backslashes in `/proc/self/mountinfo`.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func statFSError(s string) error {
+	return &InvalidLineError{"statfs", s}
+}
+
+// gatherStatFS returns the capacity of the filesystem mounted at
+// mountPoint, from `stat -f`.
+func gatherStatFS(gi *GatherInvoker, mountPoint string) (map[string]any, error) {
+	s, err := gi.Invoke("stat", "-f", "-c", "%S %b %f %a %c %d", "--", mountPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var n [6]int64
+	fields := strings.Fields(s)
+	if len(fields) != len(n) {
+		return nil, statFSError(s)
+	}
+	for i, field := range fields {
+		if n[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+			return nil, statFSError(s)
+		}
+	}
+
+	blockSize := n[0]
+	return map[string]any{
+		"size_bytes":      int(n[1] * blockSize),
+		"free_bytes":      int(n[2] * blockSize),
+		"available_bytes": int(n[3] * blockSize),
+		"inodes":          int(n[4]),
+		"inodes_free":     int(n[5]),
+	}, nil
+}
+
+// linkMounts cross-references r's mounts with its block devices.
+// Mounts whose source is a block device in r.Disks have that device
+// path in their "block_device" key.
+func (r *HostInfo) linkMounts() {
+	for _, mount := range r.Mounts {
+		source, _ := mount["source"].(string)
+		if _, found := r.Disks[source]; found {
+			mount["block_device"] = source
+		}
+	}
+}
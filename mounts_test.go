@@ -0,0 +1,118 @@
+package hostinfo
+
+import (
+	_ "embed"
+	"errors"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestGatherMounts_live(t *testing.T) {
+	r := assertExec(t, gatherMounts)
+	root := r.Mounts["/"]
+	assert.Assert(t, root != nil)
+	assert.Equal(t, root["mount_point"], "/")
+	assert.Check(t, root["size_bytes"].(int) > 0)
+}
+
+//go:embed resources/mountinfo
+var testMountInfo []byte
+
+func expectStatFS(mock *invoker.MockInvoker, mountPoint, output string) {
+	mock.ExpectInvoke("stat", "-f", "-c", "%S %b %f %a %c %d", "--", mountPoint).
+		Returns([]byte(output), nil)
+}
+
+func TestGatherMounts_mock(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/self/mountinfo").Returns(testMountInfo, nil)
+	expectStatFS(mock, "/dev", "4096 5083059 5083059 5083059 5083059 5082400\n")
+	expectStatFS(mock, "/run", "4096 1018585 1016171 1016171 5093202 5091923\n")
+	expectStatFS(mock, "/", "4096 243079237 157416357 145054640 61882368 59853414\n")
+	expectStatFS(mock, "/boot", "4096 498775 321207 287439 131072 130764\n")
+	mock.ExpectInvoke("stat", "-f", "-c", "%S %b %f %a %c %d", "--", "/boot/efi").
+		Returns(nil, errors.New("exit status 1"))
+	expectStatFS(mock, "/srv/my files", "4096 243079237 157416357 145054640 61882368 59853414\n")
+
+	r := assertMock(t, gatherMounts, mock)
+	assert.Equal(t, len(r.Mounts), 10)
+
+	assert.DeepEqual(t, r.Mounts["/"], map[string]any{
+		"mount_id":        30,
+		"parent_id":       1,
+		"major":           253,
+		"minor":           1,
+		"root":            "/",
+		"mount_point":     "/",
+		"options":         []string{"rw", "relatime"},
+		"propagation":     []string{"shared:1"},
+		"fstype":          "ext4",
+		"source":          "/dev/mapper/vgubuntu-root",
+		"super_options":   []string{"rw", "errors=remount-ro"},
+		"size_bytes":      243079237 * 4096,
+		"free_bytes":      157416357 * 4096,
+		"available_bytes": 145054640 * 4096,
+		"inodes":          61882368,
+		"inodes_free":     59853414,
+	})
+
+	bind := r.Mounts["/srv/my files"]
+	assert.Equal(t, bind["root"], "/home/gary/My Files")
+	assert.DeepEqual(t, bind["propagation"], []string{"master:1"})
+
+	// Pseudo-filesystems and failed stats have no capacities.
+	assertNotHasKey(t, r.Mounts["/proc"], "size_bytes")
+	assertNotHasKey(t, r.Mounts["/boot/efi"], "size_bytes")
+	assert.Equal(t, r.Mounts["/boot/efi"]["fstype"], "vfat")
+}
+
+func TestGatherMounts_stacked(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/self/mountinfo").Returns([]byte(""+
+		"23 28 0:22 / /mnt rw,relatime - proc proc rw\n"+
+		"24 23 0:23 / /mnt rw,relatime - sysfs sysfs rw\n"), nil)
+
+	r := assertMock(t, gatherMounts, mock)
+	assert.Equal(t, len(r.Mounts), 1)
+	assert.Equal(t, r.Mounts["/mnt"]["fstype"], "sysfs")
+	assert.DeepEqual(t, r.Mounts["/mnt"]["propagation"], []string{"private"})
+}
+
+func TestGatherMounts_invalid(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("cat", "/proc/self/mountinfo").
+		Returns([]byte("23 28 0:22 / /mnt rw,relatime proc proc rw\n"), nil)
+
+	_, err := invoke(t, mock, gatherMounts)
+	var ile *InvalidLineError
+	assert.Check(t, errors.As(err, &ile))
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	assert.Equal(t, unescapeMountInfo(`/a\040b\011c\012d\134e`), "/a b\tc\nd\\e")
+	assert.Equal(t, unescapeMountInfo(`/trailing\04`), `/trailing\04`)
+	assert.Equal(t, unescapeMountInfo(`/plain`), "/plain")
+}
+
+func TestLinkMounts(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("cat", "/proc/self/mountinfo").Returns([]byte(""+
+		"30 1 253:1 / / rw,relatime shared:1 - ext4 /dev/mapper/vgubuntu-root rw\n"+
+		"25 30 0:23 / /proc rw,relatime shared:12 - proc proc rw\n"), nil)
+	expectStatFS(mock, "/", "4096 1 1 1 1 1\n")
+
+	r, err := Gather(testctx(t), mock,
+		Only("DiskAttrs", "Mounts"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, r.Mounts["/"]["block_device"], "/dev/mapper/vgubuntu-root")
+	assert.Equal(t, r.Disks["/dev/mapper/vgubuntu-root"]["type"], "ext4")
+	assertNotHasKey(t, r.Mounts["/proc"], "block_device")
+}
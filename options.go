@@ -0,0 +1,209 @@
+package hostinfo
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// An Option configures [Gather].
+type Option func(*options)
+
+type options struct {
+	only        map[string]bool
+	skip        map[string]bool
+	concurrency int
+	timeout     time.Duration
+	timeouts    map[string]time.Duration
+	fsys        fs.FS
+	fsysSet     bool
+	root        string
+
+	normalizeUnits bool
+
+	hostConcurrency int
+	hostTimeout     time.Duration
+
+	cacheTTL time.Duration
+}
+
+// DefaultConcurrency is the default limit on the number of gatherers
+// [Gather] runs in parallel.
+const DefaultConcurrency = 4
+
+// Concurrency limits the number of gatherers [Gather] runs in
+// parallel, and the number of commands each gatherer may run in
+// parallel, to n.  Values of n less than 1 are treated as 1, which
+// runs everything sequentially.
+func Concurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = max(n, 1)
+	}
+}
+
+// Only restricts [Gather] to the named gatherers.  Names are those
+// returned by the gatherers' Name methods, for example "CPUInfo" or
+// "MemInfo"; see [Gatherers].  Multiple Only options accumulate.
+func Only(names ...string) Option {
+	return func(o *options) {
+		o.only = addNames(o.only, names)
+	}
+}
+
+// Skip prevents [Gather] from running the named gatherers.  Names
+// are as for [Only].  Multiple Skip options accumulate.
+func Skip(names ...string) Option {
+	return func(o *options) {
+		o.skip = addNames(o.skip, names)
+	}
+}
+
+// DefaultTimeout is the default time limit for each gatherer.
+const DefaultTimeout = time.Minute
+
+// Timeout limits the time each gatherer may run for to d.  Gatherers
+// that exceed their time limit are reported as failed.  A value of
+// zero removes the limit.  Use [GathererTimeout] to override the
+// limit for individual gatherers.
+func Timeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// GathererTimeout limits the time the named gatherer may run for
+// to d, overriding any limit set with [Timeout].  Names are as for
+// [Only].  A value of zero removes the limit.
+func GathererTimeout(name string, d time.Duration) Option {
+	return func(o *options) {
+		if o.timeouts == nil {
+			o.timeouts = make(map[string]time.Duration)
+		}
+		o.timeouts[name] = d
+	}
+}
+
+// WithFS causes gatherers to read files from fsys rather than from
+// the host being gathered.  Paths in fsys are relative to the host's
+// root directory, so "/etc/os-release" is read as "etc/os-release".
+// By default, files are read directly on the local host, and using
+// `cat` on hosts accessed through other invokers; a nil fsys forces
+// the use of `cat` everywhere.
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+		o.fsysSet = true
+	}
+}
+
+// WithRoot causes gatherers to read files from the directory tree
+// rooted at root rather than from "/", for inventorying disk images,
+// container filesystems and chroots.  Gatherers which require a
+// running system, for example those that read "/proc" or run `ip`,
+// are skipped; see [OfflineGatherer].  The root must be an absolute
+// path on the host being gathered; [Gather] returns an error if not.
+func WithRoot(root string) Option {
+	return func(o *options) {
+		o.root = root
+	}
+}
+
+// DefaultHostConcurrency is the default limit on the number of hosts
+// [GatherMany] gathers in parallel.
+const DefaultHostConcurrency = 16
+
+// HostConcurrency limits the number of hosts [GatherMany] gathers in
+// parallel to n.  Values of n less than 1 are treated as 1.  [Gather]
+// ignores this option.
+func HostConcurrency(n int) Option {
+	return func(o *options) {
+		o.hostConcurrency = max(n, 1)
+	}
+}
+
+// DefaultHostTimeout is the default time limit for each host gathered
+// by [GatherMany].
+const DefaultHostTimeout = 5 * time.Minute
+
+// HostTimeout limits the time [GatherMany] may spend gathering each
+// host to d.  A value of zero removes the limit.  [Gather] ignores
+// this option.
+func HostTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.hostTimeout = d
+	}
+}
+
+func addNames(set map[string]bool, names []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// newOptions returns the options resulting from applying opts.
+func newOptions(opts []Option) *options {
+	o := &options{
+		concurrency: DefaultConcurrency,
+		timeout:     DefaultTimeout,
+
+		hostConcurrency: DefaultHostConcurrency,
+		hostTimeout:     DefaultHostTimeout,
+
+		cacheTTL: DefaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// validate checks that all gatherer names in o refer to one of ops,
+// and that o's root, if any, is absolute.
+func (o *options) validate(ops []Gatherer) error {
+	if o.root != "" && !path.IsAbs(o.root) {
+		return fmt.Errorf("root %q is not an absolute path", o.root)
+	}
+
+	known := make(map[string]bool)
+	for _, op := range ops {
+		known[op.Name()] = true
+	}
+	for _, set := range []map[string]bool{o.only, o.skip} {
+		for name := range set {
+			if !known[name] {
+				return &UnknownGathererError{name}
+			}
+		}
+	}
+	for name := range o.timeouts {
+		if !known[name] {
+			return &UnknownGathererError{name}
+		}
+	}
+	return nil
+}
+
+// selects reports whether o permits running op.
+func (o *options) selects(op Gatherer) bool {
+	name := op.Name()
+	if o.only != nil && !o.only[name] {
+		return false
+	}
+	if o.root != "" && !isOffline(op) {
+		return false
+	}
+	return !o.skip[name]
+}
+
+// timeoutFor returns the time limit for the named gatherer.
+func (o *options) timeoutFor(name string) time.Duration {
+	if d, found := o.timeouts[name]; found {
+		return d
+	}
+	return o.timeout
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // gatherOSRelease gathers the content of `/etc/os-release`.
-func gatherOSRelease(gi *gatherInvoker, r *HostInfo) error {
+func gatherOSRelease(gi *GatherInvoker, r *HostInfo) error {
 	s, err := gi.ReadFile("/etc/os-release")
 	if err != nil {
 		return err
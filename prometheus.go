@@ -0,0 +1,205 @@
+package hostinfo
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WriteMetrics writes r to w in the Prometheus text exposition
+// format, for consumption by node_exporter's textfile collector.
+// Static facts are exported as gauges, or as "info" metrics with
+// the value 1 and the facts in their labels.  Metrics derived from
+// sections missing from r are omitted.
+func WriteMetrics(w io.Writer, r *HostInfo) error {
+	t := r.Typed()
+	bw := bufio.NewWriter(w)
+	mw := &metricWriter{w: bw}
+
+	if r.MachineID != "" {
+		mw.family("hostinfo_machine_info",
+			"Machine ID of the host.")
+		mw.sample(1, "machine_id", r.MachineID)
+	}
+
+	if len(r.OS) > 0 {
+		mw.family("hostinfo_os_info",
+			"Operating system, from /etc/os-release.")
+		mw.sample(1,
+			"id", t.OS.ID,
+			"id_like", t.OS.IDLike,
+			"name", t.OS.Name,
+			"pretty_name", t.OS.PrettyName,
+			"version", t.OS.Version,
+			"version_id", t.OS.VersionID)
+	}
+
+	if len(t.CPUs) > 0 {
+		mw.family("hostinfo_cpu_count",
+			"Number of logical processors.")
+		mw.sample(float64(len(t.CPUs)))
+
+		mw.family("hostinfo_cpu_info",
+			"Logical processor, from /proc/cpuinfo.")
+		for _, cpu := range t.CPUs {
+			mw.sample(1,
+				"cpu", strconv.Itoa(cpu.Index),
+				"vendor_id", cpu.VendorID,
+				"model_name", cpu.ModelName,
+				"physical_id", strconv.Itoa(cpu.PhysicalID),
+				"core_id", strconv.Itoa(cpu.CoreID))
+		}
+	}
+
+	if len(r.Memory) > 0 {
+		mw.family("hostinfo_memory_total_bytes",
+			"Total usable memory, in bytes.")
+		mw.sample(float64(t.Memory.TotalBytes))
+
+		mw.family("hostinfo_swap_total_bytes",
+			"Total swap space, in bytes.")
+		mw.sample(float64(t.Memory.SwapTotalBytes))
+	}
+
+	if len(t.Interfaces) > 0 {
+		mw.family("hostinfo_interface_info",
+			"Network interface.")
+		for _, iface := range t.Interfaces {
+			mw.sample(1,
+				"ifname", iface.Name,
+				"address", iface.MAC,
+				"link_type", iface.LinkType)
+		}
+	}
+
+	if len(t.BlockDevices) > 0 {
+		mw.family("hostinfo_block_device_info",
+			"Block device, from blkid.")
+		for _, bd := range t.BlockDevices {
+			mw.sample(1,
+				"device", bd.Device,
+				"type", bd.Type,
+				"uuid", bd.UUID,
+				"label", bd.Label)
+		}
+	}
+
+	luks := slices.DeleteFunc(slices.Clone(t.BlockDevices),
+		func(bd BlockDevice) bool { return bd.LUKS == nil })
+	if len(luks) > 0 {
+		mw.family("hostinfo_luks_keyslots",
+			"Number of keyslots in use on a LUKS device.")
+		for _, bd := range luks {
+			mw.sample(float64(len(bd.LUKS.Keyslots)), "device", bd.Device)
+		}
+	}
+
+	if mw.err != nil {
+		return mw.err
+	}
+	return bw.Flush()
+}
+
+// WriteMetricsFile writes r to the named file as [WriteMetrics]
+// does.  The file is written atomically, by writing a temporary
+// file in the same directory and renaming it, so the textfile
+// collector never reads a partially-written file.  The temporary
+// file's name does not end in ".prom", so the collector ignores it.
+func WriteMetricsFile(name string, r *HostInfo) (err error) {
+	dir, base := filepath.Dir(name), filepath.Base(name)
+	f, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = WriteMetrics(f, r); err != nil {
+		return err
+	} else if err = f.Chmod(0o644); err != nil {
+		return err
+	} else if err = f.Sync(); err != nil {
+		return err
+	} else if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// metricWriter writes metrics in the Prometheus text exposition
+// format.  Errors are sticky: after the first, nothing is written.
+type metricWriter struct {
+	w    io.StringWriter
+	name string
+	err  error
+}
+
+// family starts a new metric family.
+func (mw *metricWriter) family(name, help string) {
+	mw.name = name
+	mw.write("# HELP ", name, " ", escapeHelp(help), "\n")
+	mw.write("# TYPE ", name, " gauge\n")
+}
+
+// sample writes one sample of the current family.  Labels are given
+// as alternating names and values; labels with empty values are
+// omitted, which Prometheus treats identically.
+func (mw *metricWriter) sample(value float64, labels ...string) {
+	mw.write(mw.name)
+
+	sep := "{"
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] == "" {
+			continue
+		}
+		mw.write(sep, labels[i], `="`, escapeLabelValue(labels[i+1]), `"`)
+		sep = ","
+	}
+	if sep != "{" {
+		mw.write("}")
+	}
+
+	if value == float64(int64(value)) {
+		mw.write(" ", strconv.FormatInt(int64(value), 10), "\n")
+	} else {
+		mw.write(" ", strconv.FormatFloat(value, 'g', -1, 64), "\n")
+	}
+}
+
+func (mw *metricWriter) write(ss ...string) {
+	for _, s := range ss {
+		if mw.err != nil {
+			return
+		}
+		_, mw.err = mw.w.WriteString(s)
+	}
+}
+
+var (
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+	labelValueEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		"\n", `\n`,
+		`"`, `\"`,
+	)
+)
+
+// escapeHelp escapes s for use as a metric's help text.
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// escapeLabelValue escapes s for use as a label value.
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
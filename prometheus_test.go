@@ -0,0 +1,96 @@
+package hostinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	r := testHostInfo(t)
+	r.MachineID = "0123456789abcdef0123456789abcdef"
+
+	var sb strings.Builder
+	assert.NilError(t, WriteMetrics(&sb, r))
+	got := sb.String()
+
+	for _, want := range []string{
+		"# HELP hostinfo_cpu_count Number of logical processors.\n" +
+			"# TYPE hostinfo_cpu_count gauge\n" +
+			"hostinfo_cpu_count 12\n",
+		`hostinfo_machine_info{machine_id="0123456789abcdef0123456789abcdef"} 1` + "\n",
+		`hostinfo_os_info{id="ubuntu",id_like="debian",name="Ubuntu",` +
+			`pretty_name="Ubuntu 22.04.5 LTS",` +
+			`version="22.04.5 LTS (Jammy Jellyfish)",version_id="22.04"} 1` + "\n",
+		"hostinfo_memory_total_bytes 41721233408\n",
+		`hostinfo_interface_info{ifname="lo",address="00:00:00:00:00:00",link_type="loopback"} 1` + "\n",
+		`hostinfo_luks_keyslots{device="/dev/nvme0n1p3"} 1` + "\n",
+	} {
+		assert.Check(t, strings.Contains(got, want), want)
+	}
+	assert.Check(t, strings.HasSuffix(got, "\n"))
+}
+
+func TestWriteMetrics_empty(t *testing.T) {
+	var sb strings.Builder
+	assert.NilError(t, WriteMetrics(&sb, &HostInfo{}))
+	assert.Equal(t, sb.String(), "")
+}
+
+func TestWriteMetrics_escaping(t *testing.T) {
+	r := &HostInfo{OS: map[string]string{
+		"id":   "odd",
+		"name": "back\\slash \"quoted\"\nnewline",
+	}}
+
+	var sb strings.Builder
+	assert.NilError(t, WriteMetrics(&sb, r))
+	assert.Equal(t, sb.String(), ""+
+		"# HELP hostinfo_os_info Operating system, from /etc/os-release.\n"+
+		"# TYPE hostinfo_os_info gauge\n"+
+		`hostinfo_os_info{id="odd",name="back\\slash \"quoted\"\nnewline"} 1`+"\n")
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "hostinfo.prom")
+	assert.NilError(t, os.WriteFile(name, []byte("stale\n"), 0o644))
+
+	r := &HostInfo{MachineID: "0123456789abcdef0123456789abcdef"}
+	assert.NilError(t, WriteMetricsFile(name, r))
+
+	b, err := os.ReadFile(name)
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasPrefix(string(b), "# HELP hostinfo_machine_info "))
+
+	fi, err := os.Stat(name)
+	assert.NilError(t, err)
+	assert.Equal(t, fi.Mode().Perm(), os.FileMode(0o644))
+
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1) // no temporary files left
+}
+
+// A bare filename is written in the working directory, not $TMPDIR.
+func TestWriteMetricsFile_bareName(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("TMPDIR", filepath.Join(dir, "missing"))
+
+	assert.NilError(t, WriteMetricsFile("hostinfo.prom", &HostInfo{}))
+
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Name(), "hostinfo.prom")
+}
+
+func TestWriteMetricsFile_noDir(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "missing", "hostinfo.prom")
+	err := WriteMetricsFile(name, &HostInfo{})
+	assert.Check(t, os.IsNotExist(err))
+}
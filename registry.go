@@ -0,0 +1,152 @@
+package hostinfo
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// A Gatherer populates part of a [HostInfo].  Gatherers run in
+// parallel, each with its own empty HostInfo, which [Gather] merges
+// into the result if the gatherer returns nil.  Gatherers defined
+// outside this package should store their results in the HostInfo's
+// Extra field.
+type Gatherer interface {
+	// Name returns the name used to select the gatherer with [Only],
+	// [Skip] and [GathererTimeout], and to report its failure.
+	Name() string
+
+	// Gather populates r with information obtained using gi.
+	Gather(gi *GatherInvoker, r *HostInfo) error
+}
+
+// An OfflineGatherer is a [Gatherer] that can gather information
+// from a directory tree that is not the root of a running system.
+// Gatherers that do not implement OfflineGatherer, or whose Offline
+// method returns false, are skipped by [Gather] when [WithRoot] is
+// specified.
+type OfflineGatherer interface {
+	Gatherer
+
+	// Offline reports whether the gatherer works with [WithRoot].
+	Offline() bool
+}
+
+// isOffline reports whether op works with [WithRoot].
+func isOffline(op Gatherer) bool {
+	og, ok := op.(OfflineGatherer)
+	return ok && og.Offline()
+}
+
+// GathererFunc returns a [Gatherer] with the given name that calls f.
+func GathererFunc(
+	name string,
+	f func(gi *GatherInvoker, r *HostInfo) error,
+) Gatherer {
+	return &namedGatherer{name, f}
+}
+
+type namedGatherer struct {
+	name string
+	f    func(*GatherInvoker, *HostInfo) error
+}
+
+func (g *namedGatherer) Name() string {
+	return g.name
+}
+
+func (g *namedGatherer) Gather(gi *GatherInvoker, r *HostInfo) error {
+	return g.f(gi, r)
+}
+
+// registry holds the gatherers [Gather] selects from.
+var registry = struct {
+	sync.Mutex
+	gatherers []Gatherer
+}{
+	gatherers: []Gatherer{
+		gatherer(gatherDiskAttrs),
+		gatherer(gatherDiskTopology),
+		gatherer(gatherCPUInfo),
+		gatherer(gatherHardware),
+		gatherer(gatherKernel),
+		offlineGatherer{gatherMachineID},
+		gatherer(gatherMemInfo),
+		gatherer(gatherMounts),
+		gatherer(gatherInterfaces),
+		offlineGatherer{gatherOSRelease},
+	},
+}
+
+// Register makes a gatherer available to [Gather], which will run
+// it by default.  Register panics if the gatherer's name is empty or
+// is already registered.
+func Register(g Gatherer) {
+	name := g.Name()
+	if name == "" {
+		panic("hostinfo: Register called with unnamed gatherer")
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, op := range registry.gatherers {
+		if op.Name() == name {
+			panic(fmt.Sprintf("hostinfo: Register called twice for %q", name))
+		}
+	}
+	registry.gatherers = append(registry.gatherers, g)
+}
+
+// Gatherers returns the names of all registered gatherers, in the
+// order they were registered.
+func Gatherers() []string {
+	var names []string
+	for _, op := range registeredGatherers() {
+		names = append(names, op.Name())
+	}
+	return names
+}
+
+// registeredGatherers returns a snapshot of the registry.
+func registeredGatherers() []Gatherer {
+	registry.Lock()
+	defer registry.Unlock()
+	return append([]Gatherer(nil), registry.gatherers...)
+}
+
+// A gatherer is a function that populates part of a HostInfo.
+type gatherer func(*GatherInvoker, *HostInfo) error
+
+// Name returns the name of a gatherer, for error messages etc.
+func (op gatherer) Name() string {
+	name := runtime.FuncForPC(reflect.ValueOf(op).Pointer()).Name()
+	dot := strings.LastIndex(name, ".")
+	if dot > 0 {
+		name = name[dot+1:]
+	}
+	name, _ = strings.CutPrefix(name, "gather")
+	return name
+}
+
+// String returns the name of a gatherer.
+func (op gatherer) String() string {
+	return op.Name()
+}
+
+// Gather implements [Gatherer].
+func (op gatherer) Gather(gi *GatherInvoker, r *HostInfo) error {
+	return op(gi, r)
+}
+
+// An offlineGatherer is a gatherer that only reads files.
+type offlineGatherer struct {
+	gatherer
+}
+
+// Offline implements [OfflineGatherer].
+func (offlineGatherer) Offline() bool {
+	return true
+}
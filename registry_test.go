@@ -0,0 +1,97 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+// register registers g for the duration of a test.
+func register(t *testing.T, g Gatherer) {
+	t.Helper()
+	Register(g)
+	t.Cleanup(func() {
+		registry.Lock()
+		defer registry.Unlock()
+		registry.gatherers = slices.DeleteFunc(
+			registry.gatherers,
+			func(op Gatherer) bool { return op == g },
+		)
+	})
+}
+
+func gatherTestHostname(gi *GatherInvoker, r *HostInfo) error {
+	s, err := gi.InvokeRetrySudo("hostname")
+	if err != nil {
+		return err
+	}
+	if r.Extra == nil {
+		r.Extra = make(map[string]any)
+	}
+	r.Extra["hostname"] = strings.TrimSpace(s)
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	register(t, GathererFunc("TestHostname", gatherTestHostname))
+	assert.Check(t, slices.Contains(Gatherers(), "TestHostname"))
+	assert.Check(t, slices.Contains(Gatherers(), "CPUInfo"))
+
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("hostname").Returns([]byte("host1.example.com\n"), nil)
+	mock.ExpectInvoke("cat", "/etc/machine-id").
+		Returns([]byte("0123456789abcdef0123456789abcdef\n"), nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("TestHostname", "MachineID"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, r.Extra["hostname"], "host1.example.com")
+	assert.Equal(t, r.Meta.Gatherers["TestHostname"].Outcome, OutcomeOK)
+
+	r.Meta = nil
+	b, err := json.Marshal(r)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{`+
+		`"schema_version":"`+SchemaVersion+`",`+
+		`"machine_id":"0123456789abcdef0123456789abcdef",`+
+		`"extra":{"hostname":"host1.example.com"}}`)
+}
+
+func TestRegister_duplicate(t *testing.T) {
+	defer func() {
+		assert.Equal(t, recover(),
+			`hostinfo: Register called twice for "MemInfo"`)
+	}()
+	Register(GathererFunc("MemInfo", gatherTestHostname))
+	t.Error("expected panic")
+}
+
+func TestRegister_unnamed(t *testing.T) {
+	defer func() {
+		assert.Equal(t, recover(),
+			"hostinfo: Register called with unnamed gatherer")
+	}()
+	Register(GathererFunc("", gatherTestHostname))
+	t.Error("expected panic")
+}
+
+func TestGatherers(t *testing.T) {
+	assert.DeepEqual(t, Gatherers(), []string{
+		"DiskAttrs",
+		"DiskTopology",
+		"CPUInfo",
+		"Hardware",
+		"Kernel",
+		"MachineID",
+		"MemInfo",
+		"Mounts",
+		"Interfaces",
+		"OSRelease",
+	})
+}
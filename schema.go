@@ -0,0 +1,214 @@
+package hostinfo
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the version of the JSON serialization of [HostInfo]
+// produced by this package, as "MAJOR.MINOR".  The minor version is
+// incremented when members are added, and the major version when
+// existing members are changed or removed.
+const SchemaVersion = "1.5"
+
+//go:embed hostinfo.schema.json
+var schema []byte
+
+// Schema returns the JSON Schema describing the serialization of
+// [HostInfo] at [SchemaVersion].
+func Schema() []byte {
+	return bytes.Clone(schema)
+}
+
+// A ValidationError is returned by [Validate] for documents that do
+// not conform to the schema.
+type ValidationError struct {
+	// Path locates the offending value within the document.
+	Path Path
+
+	// Reason describes the problem.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+}
+
+// ErrIncompatibleSchema is returned (wrapped) by [Validate] for
+// documents whose major schema version differs from that of
+// [SchemaVersion].
+var ErrIncompatibleSchema = errors.New("incompatible schema version")
+
+// Validate checks that data is a serialized [HostInfo] compatible
+// with this package, that is, that its major schema version matches
+// that of [SchemaVersion], and that it conforms to [Schema].
+func Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return &ValidationError{nil, "not an object"}
+	}
+	v, found := m["schema_version"]
+	if !found {
+		return &ValidationError{Path{"schema_version"}, "missing"}
+	}
+	version, ok := v.(string)
+	if !ok {
+		return &ValidationError{
+			Path{"schema_version"},
+			fmt.Sprintf("got %s, want string", jsonType(v)),
+		}
+	}
+	if major(version) != major(SchemaVersion) {
+		return fmt.Errorf("%q: %w", version, ErrIncompatibleSchema)
+	}
+
+	var s map[string]any
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return err
+	}
+
+	return validateValue(nil, s, doc)
+}
+
+// major returns the major part of a schema version.
+func major(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
+
+// validateValue validates v against the JSON Schema s.  Only the
+// keywords used in this package's schema are supported.
+func validateValue(path Path, s map[string]any, v any) error {
+	if types := schemaTypes(s["type"]); types != nil {
+		got := jsonType(v)
+		if got == "integer" && !slices.Contains(types, got) {
+			got = "number" // every integer is a number
+		}
+		if !slices.Contains(types, got) {
+			return &ValidationError{
+				slices.Clone(path),
+				fmt.Sprintf("got %s, want %s",
+					jsonType(v), strings.Join(types, " or ")),
+			}
+		}
+	}
+
+	if pattern, ok := s["pattern"].(string); ok {
+		if str, ok := v.(string); ok {
+			if !regexp.MustCompile(pattern).MatchString(str) {
+				return &ValidationError{
+					slices.Clone(path),
+					fmt.Sprintf("%q does not match %q", str, pattern),
+				}
+			}
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		required, _ := s["required"].([]any)
+		for _, key := range required {
+			if _, found := v[key.(string)]; !found {
+				return &ValidationError{
+					append(slices.Clone(path), key.(string)),
+					"missing",
+				}
+			}
+		}
+
+		properties, _ := s["properties"].(map[string]any)
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			ps, ok := properties[key].(map[string]any)
+			if !ok {
+				ps, ok = s["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				if s["additionalProperties"] == false {
+					return &ValidationError{
+						append(slices.Clone(path), key),
+						"unexpected",
+					}
+				}
+				continue
+			}
+			err := validateValue(append(path, key), ps, v[key])
+			if err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		items, ok := s["items"].(map[string]any)
+		if !ok {
+			break
+		}
+		for i, item := range v {
+			err := validateValue(append(path, strconv.Itoa(i)), items, item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes returns the value of a "type" keyword as a slice.
+func schemaTypes(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var result []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type of v, which must have been
+// decoded with [json.Decoder.UseNumber].
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
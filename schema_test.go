@@ -0,0 +1,63 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSchema(t *testing.T) {
+	var s map[string]any
+	assert.NilError(t, json.Unmarshal(Schema(), &s))
+	assert.Equal(t, s["title"], "HostInfo")
+}
+
+func TestValidate(t *testing.T) {
+	r := testHostInfo(t)
+	r.MachineID = "0123456789abcdef0123456789abcdef"
+	r.Extra = map[string]any{"hostname": "host1"}
+	assert.Equal(t, r.SchemaVersion, SchemaVersion)
+
+	b, err := json.Marshal(r)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(b))
+}
+
+func TestValidate_compatible(t *testing.T) {
+	assert.NilError(t, Validate([]byte(`{
+		"schema_version": "1.99",
+		"new_section": {"added": "in a later minor version"}
+	}`)))
+}
+
+func TestValidate_incompatible(t *testing.T) {
+	err := Validate([]byte(`{"schema_version": "2.0"}`))
+	assert.Check(t, errors.Is(err, ErrIncompatibleSchema))
+	assert.Error(t, err, `"2.0": incompatible schema version`)
+}
+
+func TestValidate_invalid(t *testing.T) {
+	for doc, want := range map[string]string{
+		`[]`:                                  "not an object",
+		`{}`:                                  "schema_version: missing",
+		`{"schema_version":1}`:                "schema_version: got integer, want string",
+		`{"schema_version":"1.0", "cpus":{}}`: "cpus: got object, want array",
+		`{"schema_version":"1.0", "machine_id":"xyz"}`: `machine_id: ` +
+			`"xyz" does not match "^[0-9a-f]{32}$"`,
+		`{"schema_version":"1.0", "memory":{"mem_total_kb":1.5}}`: "" +
+			"memory.mem_total_kb: got number, want integer or string or boolean",
+		`{"schema_version":"1.0", "network_interfaces":` +
+			`{"eth0":{"addr_info":[{"prefixlen":"24"}]}}}`: "" +
+			"network_interfaces.eth0.addr_info.0.prefixlen: " +
+			"got string, want integer",
+		`{"schema_version":"1.0", "operating_system":{"id":7}}`: "" +
+			"operating_system.id: got integer, want string",
+	} {
+		err := Validate([]byte(doc))
+		var ve *ValidationError
+		assert.Check(t, errors.As(err, &ve), doc)
+		assert.Error(t, err, want, doc)
+	}
+}
@@ -0,0 +1,259 @@
+package hostinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gbenson.net/go/logger"
+)
+
+// DefaultCacheTTL is the default time for which a [Handler] caches
+// each section it gathers.
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheTTL causes a [Handler] to cache each section it gathers for
+// d.  A value of zero disables caching.  [Gather] ignores this
+// option.
+func CacheTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.cacheTTL = d
+	}
+}
+
+// A Handler is an [http.Handler] which serves a [HostInfo] as JSON.
+// Each gatherer's output is cached separately, so requests for a
+// single section only run the gatherer responsible.
+//
+// A GET request for "/" returns the entire HostInfo, gathering any
+// sections not in the cache.  A GET request for "/NAME" returns the
+// section gathered by the named gatherer, where NAME is a gatherer
+// name as returned by [Gatherers] and is not case-sensitive, so
+// "/interfaces" returns the output of the "Interfaces" gatherer.
+// Adding "?refresh=1" to either request bypasses the cache.  Failed
+// gatherers are not cached, and are retried by the next request.
+//
+// Responses carry an ETag, and requests whose If-None-Match header
+// matches it receive a "304 Not Modified" response.
+type Handler struct {
+	invoker invoker.Invoker
+	options *options
+	entries map[string]*cacheEntry // keyed by lowercased name
+	names   []string               // in registry order
+
+	now func() time.Time
+}
+
+// A cacheEntry caches the output of one gatherer.
+type cacheEntry struct {
+	name string
+
+	mu      sync.Mutex // held while gathering
+	info    *HostInfo
+	err     error
+	expires time.Time
+}
+
+// NewHandler returns a [Handler] which gathers the host accessed by
+// invoker.  Options are as for [Gather], and additionally [CacheTTL].
+func NewHandler(invoker invoker.Invoker, opts ...Option) (*Handler, error) {
+	o := newOptions(opts)
+	gatherers := registeredGatherers()
+	if err := o.validate(gatherers); err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		invoker: invoker,
+		options: o,
+		entries: make(map[string]*cacheEntry),
+		now:     time.Now,
+	}
+	for _, op := range gatherers {
+		if !o.selects(op) {
+			continue
+		}
+		name := op.Name()
+		h.entries[strings.ToLower(name)] = &cacheEntry{name: name}
+		h.names = append(h.names, name)
+	}
+	if len(h.names) == 0 {
+		return nil, errors.New("no gatherers selected")
+	}
+
+	return h, nil
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var entries []*cacheEntry
+	if section := strings.Trim(req.URL.Path, "/"); section == "" {
+		for _, name := range h.names {
+			entries = append(entries, h.entries[strings.ToLower(name)])
+		}
+	} else if e, found := h.entries[strings.ToLower(section)]; found {
+		entries = append(entries, e)
+	} else {
+		http.NotFound(w, req)
+		return
+	}
+
+	var refresh bool
+	if s := req.URL.Query().Get("refresh"); s != "" {
+		var err error
+		if refresh, err = strconv.ParseBool(s); err != nil {
+			http.Error(w, "invalid refresh parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Gathering continues if the client goes away, so the result
+	// can be cached for the next request.
+	ctx := context.WithoutCancel(req.Context())
+
+	parallel(len(entries), h.options.concurrency, func(i int) {
+		h.update(ctx, entries[i], refresh)
+	})
+
+	result, err := h.compose(entries)
+	if err != nil {
+		logger.Ctx(ctx).Warn().
+			Err(err).
+			Str("path", req.URL.Path).
+			Msg("Gather failed")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+// update gathers e's section, unless it is cached and refresh is
+// false.  Failures are not cached, so the next request retries.
+func (h *Handler) update(ctx context.Context, e *cacheEntry, refresh bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !refresh && h.now().Before(e.expires) {
+		return
+	}
+
+	o := *h.options
+	o.only = map[string]bool{e.name: true}
+	o.skip = nil
+
+	e.info, e.err = gather(ctx, h.invoker, &o)
+	if e.err == nil {
+		e.expires = h.now().Add(o.cacheTTL)
+	} else {
+		e.expires = time.Time{}
+	}
+}
+
+// compose returns the merged sections of entries.  If entries has
+// more than one element then failed sections are reported in the
+// result's Partial field, unless every section failed, in which
+// case the returned error is a [GatherError].
+func (h *Handler) compose(entries []*cacheEntry) (*HostInfo, error) {
+	result := &HostInfo{SchemaVersion: SchemaVersion}
+	failed := &GatherError{}
+
+	for _, e := range entries {
+		e.mu.Lock()
+		info, err := e.info, e.err
+		e.mu.Unlock()
+
+		if err != nil {
+			var ge *GatherError
+			if errors.As(err, &ge) {
+				for name, err := range ge.Errors {
+					failed.add(name, err)
+				}
+			} else {
+				failed.add(e.name, err)
+			}
+			continue
+		}
+
+		result.merge(info)
+		result.Meta = mergeMeta(result.Meta, info.Meta)
+	}
+	result.linkMounts()
+
+	if len(failed.Errors) == len(entries) {
+		return nil, failed
+	} else if len(failed.Errors) > 0 {
+		result.Partial = failed
+	}
+
+	return result, nil
+}
+
+// mergeMeta merges src into dst, allocating dst if necessary, and
+// returns dst.  The result's GatheredAt is that of the oldest input.
+func mergeMeta(dst, src *Meta) *Meta {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &Meta{
+			GatheredAt: src.GatheredAt,
+			Tool:       src.Tool,
+			Version:    src.Version,
+			Gatherers:  make(map[string]*GathererMeta),
+		}
+	}
+	if src.GatheredAt.Before(dst.GatheredAt) {
+		dst.GatheredAt = src.GatheredAt
+	}
+	dst.Duration = max(dst.Duration, src.Duration)
+	maps.Copy(dst.Gatherers, src.Gatherers)
+	return dst
+}
+
+// etagMatches reports whether the value of an If-None-Match header
+// matches etag, using the weak comparison function.
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	return slices.ContainsFunc(strings.Split(header, ","), func(s string) bool {
+		s = strings.TrimPrefix(strings.TrimSpace(s), "W/")
+		return s == etag
+	})
+}
@@ -0,0 +1,222 @@
+package hostinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+// countingInvoker counts the invocations of each command.
+type countingInvoker struct {
+	invoker.Invoker
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (ci *countingInvoker) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	command := strings.Join(append([]string{name}, arg...), " ")
+	ci.mu.Lock()
+	if ci.counts == nil {
+		ci.counts = make(map[string]int)
+	}
+	ci.counts[command]++
+	ci.mu.Unlock()
+	return ci.Invoker.Invoke(ctx, name, arg...)
+}
+
+func (ci *countingInvoker) count(command string) int {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	return ci.counts[command]
+}
+
+func newTestHandler(t *testing.T, opts ...Option) (*Handler, *countingInvoker) {
+	t.Helper()
+	ci := &countingInvoker{Invoker: &cannedInvoker{outputs: map[string][]byte{
+		"cat /proc/meminfo":   testMemInfo,
+		"cat /etc/os-release": ubuntuOSRelease,
+	}}}
+	opts = append([]Option{Only("MemInfo", "OSRelease", "MachineID")}, opts...)
+	h, err := NewHandler(ci, opts...)
+	assert.NilError(t, err)
+	return h, ci
+}
+
+func serve(t *testing.T, h http.Handler, target string, header ...string) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req = req.WithContext(testctx(t))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func decodeResponse(t *testing.T, resp *http.Response) *HostInfo {
+	t.Helper()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "application/json")
+	var r HostInfo
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&r))
+	return &r
+}
+
+func TestHandler(t *testing.T) {
+	h, ci := newTestHandler(t)
+
+	r := decodeResponse(t, serve(t, h, "/"))
+	assert.Equal(t, r.SchemaVersion, SchemaVersion)
+	assert.Equal(t, len(r.Memory), 55)
+	assert.Equal(t, r.OS["id"], "ubuntu")
+	assert.Equal(t, r.MachineID, "") // failed
+	assert.Equal(t, len(r.Meta.Gatherers), 2)
+
+	// Everything but the failure is cached.
+	decodeResponse(t, serve(t, h, "/"))
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 1)
+	assert.Equal(t, ci.count("cat /etc/os-release"), 1)
+	assert.Equal(t, ci.count("cat /etc/machine-id"), 2)
+}
+
+func TestHandler_retry(t *testing.T) {
+	h, ci := newTestHandler(t)
+
+	resp := serve(t, h, "/machineid")
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+
+	ci.Invoker.(*cannedInvoker).set("cat /etc/machine-id",
+		[]byte("0123456789abcdef0123456789abcdef\n"))
+	r := decodeResponse(t, serve(t, h, "/machineid"))
+	assert.Equal(t, r.MachineID, "0123456789abcdef0123456789abcdef")
+	assert.Equal(t, ci.count("cat /etc/machine-id"), 2)
+
+	// The success is cached.
+	decodeResponse(t, serve(t, h, "/machineid"))
+	assert.Equal(t, ci.count("cat /etc/machine-id"), 2)
+}
+
+// A refresh that fails must not leave the failure cached until the
+// previous success would have expired.
+func TestHandler_failedRefresh(t *testing.T) {
+	h, ci := newTestHandler(t, CacheTTL(time.Hour))
+	cmd := "cat /etc/machine-id"
+	ci.Invoker.(*cannedInvoker).set(cmd,
+		[]byte("0123456789abcdef0123456789abcdef\n"))
+
+	decodeResponse(t, serve(t, h, "/machineid"))
+	assert.Equal(t, ci.count(cmd), 1)
+
+	ci.Invoker.(*cannedInvoker).set(cmd, nil)
+	resp := serve(t, h, "/machineid?refresh=1")
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, ci.count(cmd), 2)
+
+	ci.Invoker.(*cannedInvoker).set(cmd,
+		[]byte("fedcba9876543210fedcba9876543210\n"))
+	r := decodeResponse(t, serve(t, h, "/machineid"))
+	assert.Equal(t, r.MachineID, "fedcba9876543210fedcba9876543210")
+	assert.Equal(t, ci.count(cmd), 3)
+}
+
+func TestHandler_section(t *testing.T) {
+	h, ci := newTestHandler(t)
+
+	r := decodeResponse(t, serve(t, h, "/meminfo"))
+	assert.Equal(t, len(r.Memory), 55)
+	assert.Equal(t, len(r.OS), 0)
+	assert.Equal(t, ci.count("cat /etc/os-release"), 0)
+
+	decodeResponse(t, serve(t, h, "/MemInfo/"))
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 1)
+
+	resp := serve(t, h, "/machineid")
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+
+	resp = serve(t, h, "/interfaces") // not selected
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+}
+
+func TestHandler_refresh(t *testing.T) {
+	h, ci := newTestHandler(t)
+
+	decodeResponse(t, serve(t, h, "/"))
+	decodeResponse(t, serve(t, h, "/osrelease?refresh=1"))
+	assert.Equal(t, ci.count("cat /etc/os-release"), 2)
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 1)
+
+	resp := serve(t, h, "/?refresh=please")
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+}
+
+func TestHandler_ttl(t *testing.T) {
+	h, ci := newTestHandler(t, CacheTTL(time.Minute))
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	decodeResponse(t, serve(t, h, "/meminfo"))
+	now = now.Add(59 * time.Second)
+	decodeResponse(t, serve(t, h, "/meminfo"))
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 1)
+
+	now = now.Add(time.Second)
+	decodeResponse(t, serve(t, h, "/meminfo"))
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 2)
+}
+
+func TestHandler_noCache(t *testing.T) {
+	h, ci := newTestHandler(t, CacheTTL(0))
+
+	decodeResponse(t, serve(t, h, "/meminfo"))
+	decodeResponse(t, serve(t, h, "/meminfo"))
+	assert.Equal(t, ci.count("cat /proc/meminfo"), 2)
+}
+
+func TestHandler_etag(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	resp := serve(t, h, "/")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	etag := resp.Header.Get("ETag")
+	assert.Check(t, strings.HasPrefix(etag, `"`))
+
+	resp = serve(t, h, "/", "If-None-Match", etag)
+	assert.Equal(t, resp.StatusCode, http.StatusNotModified)
+	assert.Equal(t, resp.Header.Get("ETag"), etag)
+
+	resp = serve(t, h, "/", "If-None-Match", `"other", W/`+etag)
+	assert.Equal(t, resp.StatusCode, http.StatusNotModified)
+
+	resp = serve(t, h, "/meminfo", "If-None-Match", etag)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Check(t, resp.Header.Get("ETag") != etag)
+}
+
+func TestHandler_method(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+	assert.Equal(t, w.Header().Get("Allow"), "GET, HEAD")
+}
+
+func TestNewHandler_unknown(t *testing.T) {
+	_, err := NewHandler(invoker.NewMock(t), Only("Disks"))
+	assert.Error(t, err, `"Disks": unknown gatherer`)
+}
@@ -0,0 +1,130 @@
+package hostinfo
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gbenson.net/go/invoker"
+)
+
+// An SSHInvoker is an [invoker.Invoker] that runs commands on a
+// remote host using ssh(1), so hosts can be gathered without first
+// installing anything on them.  Authentication, host key checking
+// and everything else not configured here follows the local user's
+// ssh configuration.  ssh is run in batch mode, so it fails rather
+// than prompting for passwords or passphrases.
+type SSHInvoker struct {
+	// Host is the host to connect to.  It may be a hostname, an
+	// address, or an alias from ssh_config(5), and may be prefixed
+	// with "user@".
+	Host string
+
+	// User and Port, if set, override ssh's defaults.
+	User string
+	Port int
+
+	// IdentityFile, if set, is the private key to authenticate with.
+	IdentityFile string
+
+	// KnownHostsFile, if set, replaces the user's known hosts file.
+	KnownHostsFile string
+
+	// StrictHostKeyChecking, if set, is passed to ssh as the option
+	// of the same name, for example "yes" or "accept-new".
+	StrictHostKeyChecking string
+
+	// ForwardAgent enables forwarding of the local ssh-agent.
+	ForwardAgent bool
+
+	// JumpHosts are hosts to connect through, in order, as for the
+	// ProxyJump option.
+	JumpHosts []string
+
+	// Options are extra options to pass to ssh, in "Name=value"
+	// form, for example "ConnectTimeout=10".
+	Options []string
+
+	// Invoker runs ssh.  The default is [invoker.Exec].
+	Invoker invoker.Invoker
+}
+
+// Invoke implements [invoker.Invoker].  Commands named "sudo" are
+// run with sudo's "-n" option, so sudo fails rather than prompting
+// for a password, allowing [GatherInvoker.InvokeRetrySudo] to report
+// the original error.
+func (si *SSHInvoker) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	if name == "sudo" {
+		arg = append([]string{"-n"}, arg...)
+	}
+
+	inv := si.Invoker
+	if inv == nil {
+		inv = invoker.Exec
+	}
+
+	return inv.Invoke(ctx, "ssh", si.Args(name, arg...)...)
+}
+
+// Args returns the arguments with which ssh is run to invoke the
+// given command.
+func (si *SSHInvoker) Args(name string, arg ...string) []string {
+	args := []string{"-T", "-o", "BatchMode=yes"}
+
+	if si.User != "" {
+		args = append(args, "-l", si.User)
+	}
+	if si.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(si.Port))
+	}
+	if si.IdentityFile != "" {
+		args = append(args, "-i", si.IdentityFile)
+	}
+	if si.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+si.KnownHostsFile)
+	}
+	if si.StrictHostKeyChecking != "" {
+		args = append(args, "-o",
+			"StrictHostKeyChecking="+si.StrictHostKeyChecking)
+	}
+	if si.ForwardAgent {
+		args = append(args, "-A")
+	} else {
+		args = append(args, "-a")
+	}
+	if len(si.JumpHosts) > 0 {
+		args = append(args, "-J", strings.Join(si.JumpHosts, ","))
+	}
+	for _, option := range si.Options {
+		args = append(args, "-o", option)
+	}
+
+	// ssh concatenates its trailing arguments and passes the result
+	// to the remote user's shell, so everything must be quoted.
+	return append(args, "--", si.Host, shellJoin(name, arg...))
+}
+
+// shellSafeRx matches strings that need no quoting in POSIX shells.
+var shellSafeRx = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes s for use as a single word in POSIX shells.
+func shellQuote(s string) string {
+	if shellSafeRx.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin quotes a command and its arguments for POSIX shells.
+func shellJoin(name string, arg ...string) string {
+	words := []string{shellQuote(name)}
+	for _, s := range arg {
+		words = append(words, shellQuote(s))
+	}
+	return strings.Join(words, " ")
+}
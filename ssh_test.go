@@ -0,0 +1,105 @@
+package hostinfo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestSSHInvoker(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("ssh",
+		"-T", "-o", "BatchMode=yes",
+		"-l", "gbenson",
+		"-p", "2222",
+		"-i", "/home/gbenson/.ssh/id_ed25519",
+		"-o", "UserKnownHostsFile=/etc/hostinfo/known_hosts",
+		"-o", "StrictHostKeyChecking=yes",
+		"-A",
+		"-J", "bastion.example.com,admin@10.0.0.1:2022",
+		"-o", "ConnectTimeout=10",
+		"--", "db1.example.com", "cat /etc/machine-id",
+	).Returns([]byte("0123456789abcdef0123456789abcdef\n"), nil)
+
+	si := &SSHInvoker{
+		Host:                  "db1.example.com",
+		User:                  "gbenson",
+		Port:                  2222,
+		IdentityFile:          "/home/gbenson/.ssh/id_ed25519",
+		KnownHostsFile:        "/etc/hostinfo/known_hosts",
+		StrictHostKeyChecking: "yes",
+		ForwardAgent:          true,
+		JumpHosts: []string{
+			"bastion.example.com",
+			"admin@10.0.0.1:2022",
+		},
+		Options: []string{"ConnectTimeout=10"},
+		Invoker: mock,
+	}
+
+	r, err := Gather(testctx(t), si, Only("MachineID"))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, r.MachineID, "0123456789abcdef0123456789abcdef")
+}
+
+func TestSSHInvoker_retrySudo(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("ssh",
+		"-T", "-o", "BatchMode=yes", "-a",
+		"--", "host1", "cryptsetup luksDump /dev/sda3",
+	).Returns(nil, errors.New("exit status 1"))
+	mock.ExpectInvoke("ssh",
+		"-T", "-o", "BatchMode=yes", "-a",
+		"--", "host1", "sudo -n cryptsetup luksDump /dev/sda3",
+	).Returns(luksdump, nil)
+
+	si := &SSHInvoker{Host: "host1", Invoker: mock}
+	gi := &GatherInvoker{context: testctx(t), invoker: si}
+
+	luks, err := gatherLUKSInfo(gi, "/dev/sda3")
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, luks["version"], 2)
+}
+
+// shellInvoker runs the remote commands that [SSHInvoker] passes to
+// ssh using the local shell, as the remote user's shell would.
+type shellInvoker struct{}
+
+func (shellInvoker) Invoke(
+	ctx context.Context,
+	name string,
+	arg ...string,
+) ([]byte, error) {
+	return invoker.Exec.Invoke(ctx, "sh", "-c", arg[len(arg)-1])
+}
+
+func TestSSHInvoker_quoting(t *testing.T) {
+	args := []string{
+		"plain",
+		"",
+		"with space",
+		"it's",
+		`"double"`,
+		`back\slash`,
+		"$HOME",
+		"`id`",
+		"*",
+		"semi;colon",
+		"new\nline",
+		"--flag=value",
+	}
+
+	si := &SSHInvoker{Host: "localhost", Invoker: shellInvoker{}}
+	format := []string{`%s\0`}
+	out, err := si.Invoke(testctx(t), "printf", append(format, args...)...)
+	assert.NilError(t, err)
+
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	assert.DeepEqual(t, got, args)
+}
@@ -0,0 +1,354 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+)
+
+// A TypedHostInfo is a typed view of a [HostInfo], returned by
+// [HostInfo.Typed].  It contains only the most commonly used values;
+// the HostInfo remains the source of truth.  Values missing from the
+// HostInfo are represented by zero values.
+type TypedHostInfo struct {
+	CPUs         []CPU
+	Memory       MemoryInfo
+	Interfaces   []NetInterface
+	BlockDevices []BlockDevice
+	MachineID    string
+	OS           OSRelease
+}
+
+// A CPU describes one processor listed in "/proc/cpuinfo".
+type CPU struct {
+	Index      int
+	VendorID   string
+	ModelName  string
+	PhysicalID int
+	CoreID     int
+	MHz        float64
+	Flags      []string
+}
+
+// A MemoryInfo summarizes "/proc/meminfo".
+type MemoryInfo struct {
+	TotalBytes     uint64
+	FreeBytes      uint64
+	AvailableBytes uint64
+	SwapTotalBytes uint64
+	SwapFreeBytes  uint64
+}
+
+// A NetInterface describes one network interface.
+type NetInterface struct {
+	Index     int
+	Name      string
+	LinkType  string
+	MAC       string
+	MTU       int
+	OperState string
+	Flags     []string
+	Addresses []netip.Prefix
+}
+
+// A BlockDevice describes one block device listed by `blkid` or
+// `lsblk`.  Type is the type of its content, such as "ext4", and
+// DeviceType is the type of the device itself, such as "part".
+// Parents and Children are the paths of the devices it is built on
+// and that are built on it.
+type BlockDevice struct {
+	Device     string
+	UUID       string
+	PartUUID   string
+	Label      string
+	Type       string
+	BlockSize  int
+	LUKS       *LUKSHeader
+	DeviceType string
+	SizeBytes  uint64
+	Parents    []string
+	Children   []string
+}
+
+// A LUKSHeader describes the header of a LUKS-encrypted device.
+type LUKSHeader struct {
+	Version      int
+	UUID         string
+	Epoch        int
+	DataSegments []LUKSSegment
+	Keyslots     []LUKSKeyslot
+}
+
+// A LUKSSegment describes one data segment of a LUKS device.
+type LUKSSegment struct {
+	Type        string
+	Cipher      string
+	OffsetBytes uint64
+	SectorBytes int
+}
+
+// A LUKSKeyslot describes one keyslot of a LUKS device.
+type LUKSKeyslot struct {
+	Type     string
+	Cipher   string
+	KeyBits  int
+	Priority string
+	PBKDF    string
+}
+
+// An OSRelease summarizes "/etc/os-release".
+type OSRelease struct {
+	ID         string
+	IDLike     string
+	Name       string
+	PrettyName string
+	Version    string
+	VersionID  string
+}
+
+// Typed returns a typed view of r.  The view is a copy: subsequent
+// changes to r are not reflected in it, or vice versa.
+func (r *HostInfo) Typed() *TypedHostInfo {
+	t := &TypedHostInfo{
+		Memory:    typedMemoryInfo(r.Memory),
+		MachineID: r.MachineID,
+		OS: OSRelease{
+			ID:         r.OS["id"],
+			IDLike:     r.OS["id_like"],
+			Name:       r.OS["name"],
+			PrettyName: r.OS["pretty_name"],
+			Version:    r.OS["version"],
+			VersionID:  r.OS["version_id"],
+		},
+	}
+
+	for i, cpu := range r.CPUs {
+		t.CPUs = append(t.CPUs, typedCPU(i, r.CPUInfo, cpu))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(r.Interfaces)) {
+		iface := typedNetInterface(name, r.Interfaces[name])
+		t.Interfaces = append(t.Interfaces, iface)
+	}
+	slices.SortStableFunc(t.Interfaces, func(a, b NetInterface) int {
+		return a.Index - b.Index
+	})
+
+	for _, device := range slices.Sorted(maps.Keys(r.Disks)) {
+		bd := typedBlockDevice(device, r.Disks[device])
+		t.BlockDevices = append(t.BlockDevices, bd)
+	}
+
+	return t
+}
+
+func typedCPU(index int, template, m map[string]any) CPU {
+	get := func(key string) any {
+		if v, found := m[key]; found {
+			return v
+		}
+		return template[key]
+	}
+
+	flags := asStrings(get("flags"))
+	if flags == nil {
+		flags = asStrings(get("features"))
+	}
+
+	return CPU{
+		Index:      index,
+		VendorID:   asString(get("vendor_id")),
+		ModelName:  asString(get("model_name")),
+		PhysicalID: asInt(get("physical_id")),
+		CoreID:     asInt(get("core_id")),
+		MHz:        asFloat(get("cpu_mhz")),
+		Flags:      flags,
+	}
+}
+
+func typedMemoryInfo(m map[string]any) MemoryInfo {
+	bytes := func(key string) uint64 {
+		if v, found := m[key+"_bytes"]; found {
+			return uint64(asInt(v)) // normalized
+		}
+		return uint64(asInt(m[key+"_kb"])) << 10
+	}
+
+	return MemoryInfo{
+		TotalBytes:     bytes("mem_total"),
+		FreeBytes:      bytes("mem_free"),
+		AvailableBytes: bytes("mem_available"),
+		SwapTotalBytes: bytes("swap_total"),
+		SwapFreeBytes:  bytes("swap_free"),
+	}
+}
+
+func typedNetInterface(name string, m map[string]any) NetInterface {
+	iface := NetInterface{
+		Index:     asInt(m["ifindex"]),
+		Name:      name,
+		LinkType:  asString(m["link_type"]),
+		MAC:       asString(m["address"]),
+		MTU:       asInt(m["mtu"]),
+		OperState: asString(m["operstate"]),
+		Flags:     asStrings(m["flags"]),
+	}
+
+	for _, addr := range asMaps(m["addr_info"]) {
+		ip, err := netip.ParseAddr(asString(addr["local"]))
+		if err != nil {
+			continue
+		}
+		prefix, err := ip.Prefix(asInt(addr["prefixlen"]))
+		if err != nil {
+			continue
+		}
+		// Prefix masks off the host bits, which we want to keep.
+		prefix = netip.PrefixFrom(ip, prefix.Bits())
+		iface.Addresses = append(iface.Addresses, prefix)
+	}
+
+	return iface
+}
+
+func typedBlockDevice(device string, m map[string]any) BlockDevice {
+	bd := BlockDevice{
+		Device:    device,
+		UUID:      asString(m["uuid"]),
+		PartUUID:  asString(m["partuuid"]),
+		Label:     asString(m["label"]),
+		Type:      asString(m["type"]),
+		BlockSize: asInt(m["block_size"]),
+
+		DeviceType: asString(m["device_type"]),
+		SizeBytes:  uint64(asInt(m["size_bytes"])),
+		Parents:    asStrings(m["parents"]),
+		Children:   asStrings(m["children"]),
+	}
+
+	if luks, ok := m["luks"].(map[string]any); ok {
+		bd.LUKS = typedLUKSHeader(luks)
+	}
+
+	return bd
+}
+
+func typedLUKSHeader(m map[string]any) *LUKSHeader {
+	h := &LUKSHeader{
+		Version: asInt(m["version"]),
+		UUID:    asString(m["uuid"]),
+		Epoch:   asInt(m["epoch"]),
+	}
+
+	for _, s := range asMaps(m["data_segments"]) {
+		h.DataSegments = append(h.DataSegments, LUKSSegment{
+			Type:        asString(s["type"]),
+			Cipher:      asString(s["cipher"]),
+			OffsetBytes: uint64(asInt(s["offset_bytes"])),
+			SectorBytes: asInt(s["sector_bytes"]),
+		})
+	}
+
+	for _, ks := range asMaps(m["keyslots"]) {
+		keyBits := asInt(ks["key_bits"])
+		if v, found := ks["key_bytes"]; found {
+			keyBits = asInt(v) * 8 // normalized
+		}
+
+		h.Keyslots = append(h.Keyslots, LUKSKeyslot{
+			Type:     asString(ks["type"]),
+			Cipher:   asString(ks["cipher"]),
+			KeyBits:  keyBits,
+			Priority: asString(ks["priority"]),
+			PBKDF:    asString(ks["pbkdf"]),
+		})
+	}
+
+	return h
+}
+
+// asString returns v if it is a string, or "" otherwise.
+func asString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// asInt returns v as an int, whether v was gathered or unmarshaled
+// from JSON, or 0 if v is not an integer.
+func asInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case uint64:
+		return int(n)
+	case float64:
+		if n == float64(int(n)) {
+			return int(n)
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return int(i)
+		}
+	}
+	return 0
+}
+
+// asFloat returns v as a float64, or 0 if v is not a number.  Floats
+// gathered from "/proc" are strings, and are parsed.
+func asFloat(v any) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		if f, err := strconv.ParseFloat(n, 64); err == nil {
+			return f
+		}
+	case json.Number:
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return float64(asInt(v))
+}
+
+// asStrings returns v as a []string, whether v was gathered or
+// unmarshaled from JSON, or nil if v is not a list of strings.
+func asStrings(v any) []string {
+	switch s := v.(type) {
+	case []string:
+		return slices.Clone(s)
+	case []any:
+		var result []string
+		for _, item := range s {
+			str, ok := item.(string)
+			if !ok {
+				return nil
+			}
+			result = append(result, str)
+		}
+		return result
+	}
+	return nil
+}
+
+// asMaps returns v as a []map[string]any, whether v was gathered or
+// unmarshaled from JSON.  Items that are not maps are omitted.
+func asMaps(v any) []map[string]any {
+	switch s := v.(type) {
+	case []map[string]any:
+		return s
+	case []any:
+		var result []map[string]any
+		for _, item := range s {
+			if m, ok := item.(map[string]any); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
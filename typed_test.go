@@ -0,0 +1,114 @@
+package hostinfo
+
+import (
+	"net/netip"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestTyped(t *testing.T) {
+	// testHostInfo is round-tripped through JSON.
+	assertTyped(t, testHostInfo(t).Typed())
+}
+
+func TestTyped_gathered(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("cat", "/proc/cpuinfo").
+		Returns(x86CPUInfo, nil)
+	mock.ExpectInvoke("cat", "/proc/meminfo").
+		Returns(testMemInfo, nil)
+	mock.ExpectInvoke("ip", "--json", "address", "show").
+		Returns(testInterfaces, nil)
+	mock.ExpectInvoke("cat", "/etc/os-release").
+		Returns(ubuntuOSRelease, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("DiskAttrs", "CPUInfo", "MemInfo", "Interfaces", "OSRelease"),
+		Concurrency(1))
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+	assertTyped(t, r.Typed())
+}
+
+func assertTyped(t *testing.T, r *TypedHostInfo) {
+	t.Helper()
+
+	assert.Equal(t, len(r.CPUs), 12)
+	cpu := r.CPUs[6]
+	assert.Equal(t, cpu.Index, 6)
+	assert.Equal(t, cpu.VendorID, "GenuineIntel")
+	assert.Equal(t, cpu.ModelName, "12th Gen Intel(R) Core(TM) i7-1255U")
+	assert.Equal(t, cpu.MHz, 3443.199)
+	assert.Equal(t, len(cpu.Flags), len(r.CPUs[7].Flags))
+	assert.Assert(t, len(cpu.Flags) > 100)
+	assert.Equal(t, cpu.Flags[0], "3dnowprefetch")
+	assert.Equal(t, r.CPUs[7].MHz, float64(400))
+
+	assert.Equal(t, r.Memory.TotalBytes, uint64(40_743_392*1024))
+	assert.Equal(t, r.Memory.SwapTotalBytes, uint64(2_002_940*1024))
+
+	assert.Equal(t, len(r.Interfaces), 5)
+	lo := r.Interfaces[0]
+	assert.Equal(t, lo.Name, "lo")
+	assert.Equal(t, lo.MTU, 65536)
+	assert.DeepEqual(t, lo.Addresses, []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/8"),
+		netip.MustParsePrefix("::1/128"),
+	}, cmpPrefix)
+
+	var eth0 *NetInterface
+	for i := range r.Interfaces {
+		if r.Interfaces[i].Name == "eth0" {
+			eth0 = &r.Interfaces[i]
+		}
+	}
+	assert.Assert(t, eth0 != nil)
+	assert.Equal(t, eth0.MAC, "00:16:3e:ba:ab:67")
+	assert.Equal(t, eth0.LinkType, "ether")
+	assert.DeepEqual(t, eth0.Addresses, []netip.Prefix{
+		netip.MustParsePrefix("100.115.92.201/28"),
+		netip.MustParsePrefix("fe80::216:3eff:feba:ab67/64"),
+	}, cmpPrefix)
+
+	assert.Equal(t, len(r.BlockDevices), 6)
+	bd := r.BlockDevices[3]
+	assert.Equal(t, bd.Device, "/dev/nvme0n1p1")
+	assert.Equal(t, bd.UUID, "8B92-BD41")
+	assert.Check(t, bd.LUKS == nil)
+	assert.Equal(t, r.BlockDevices[4].BlockSize, 4096)
+
+	luks := r.BlockDevices[5].LUKS
+	assert.Assert(t, luks != nil)
+	assert.DeepEqual(t, luks, &LUKSHeader{
+		Version: 2,
+		UUID:    "242e637a-461b-d087-c66e-384d35525691",
+		Epoch:   3,
+		DataSegments: []LUKSSegment{{
+			Type:        "crypt",
+			Cipher:      "aes-xts-plain64",
+			OffsetBytes: 16777216,
+			SectorBytes: 512,
+		}},
+		Keyslots: []LUKSKeyslot{{
+			Type:     "luks2",
+			Cipher:   "aes-xts-plain64",
+			KeyBits:  512,
+			Priority: "normal",
+			PBKDF:    "argon2id",
+		}},
+	})
+
+	assert.Equal(t, r.OS.ID, "ubuntu")
+	assert.Equal(t, r.OS.VersionID, "22.04")
+}
+
+var cmpPrefix = cmp.Comparer(func(a, b netip.Prefix) bool {
+	return a == b
+})
@@ -0,0 +1,108 @@
+package hostinfo
+
+import "strings"
+
+// unitScales maps the key suffixes of dimensioned values to the
+// number of bytes in one unit.  The kilo-, mega- and gigabytes of
+// "/proc/meminfo" and friends are binary multiples, despite their
+// names.
+var unitScales = []struct {
+	suffix string
+	scale  float64
+}{
+	{"_kb", 1 << 10},
+	{"_mb", 1 << 20},
+	{"_gb", 1 << 30},
+	{"_bits", 1.0 / 8},
+}
+
+// NormalizeUnits causes [Gather] to call [HostInfo.NormalizeUnits]
+// on its result, so all sizes are reported in bytes.
+func NormalizeUnits() Option {
+	return func(o *options) {
+		o.normalizeUnits = true
+	}
+}
+
+// NormalizeUnits rewrites dimensioned sizes throughout r so they are
+// reported in bytes, under keys with the suffix "_bytes".  For
+// example, "mem_total_kb": 16 becomes "mem_total_bytes": 16384, and
+// the LUKS key size "key_bits": 512 becomes "key_bytes": 64.  Values
+// that are not sizes, such as counts of pages, are left unchanged,
+// as are bit counts which are not whole numbers of bytes.  Calling
+// NormalizeUnits on a HostInfo which is already normalized has no
+// effect.
+func (r *HostInfo) NormalizeUnits() {
+	for _, m := range r.Disks {
+		normalizeUnits(m)
+	}
+	for _, m := range r.CPUs {
+		normalizeUnits(m)
+	}
+	normalizeUnits(r.CPUInfo)
+	normalizeUnits(r.Memory)
+	for _, m := range r.Interfaces {
+		normalizeUnits(m)
+	}
+}
+
+// normalizeUnits normalizes the keys and values of m, and of any
+// mappings nested within it.
+func normalizeUnits(m map[string]any) {
+	for key, value := range m {
+		normalizeNestedUnits(value)
+
+		newKey, newValue, ok := normalizeUnit(key, value)
+		if !ok {
+			continue
+		} else if _, exists := m[newKey]; exists {
+			continue // don't clobber anything
+		}
+		delete(m, key)
+		m[newKey] = newValue
+	}
+}
+
+// normalizeNestedUnits normalizes any mappings within v.
+func normalizeNestedUnits(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		normalizeUnits(v)
+	case []map[string]any:
+		for _, m := range v {
+			normalizeUnits(m)
+		}
+	case []any:
+		for _, e := range v {
+			normalizeNestedUnits(e)
+		}
+	}
+}
+
+// normalizeUnit returns the normalized key and value for the given
+// key and value, and whether normalization was possible.
+func normalizeUnit(key string, value any) (string, any, bool) {
+	for _, u := range unitScales {
+		base, found := strings.CutSuffix(key, u.suffix)
+		if !found || base == "" {
+			continue
+		}
+
+		var n float64
+		switch v := value.(type) {
+		case int:
+			n = float64(v)
+		case float64:
+			n = v // unmarshaled from JSON
+		default:
+			return "", nil, false
+		}
+
+		n *= u.scale
+		if n != float64(int(n)) {
+			return "", nil, false // fractional bytes
+		}
+		return base + "_bytes", int(n), true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,115 @@
+package hostinfo
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"gbenson.net/go/invoker"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseLine_units(t *testing.T) {
+	p := &keyValuePairParser{"units_test"}
+	for _, tc := range []struct {
+		line  string
+		key   string
+		value any
+	}{
+		{"MemTotal:  16 kB", "mem_total_kb", 16},
+		{"Foo: 2 MB", "foo_mb", 2},
+		{"Bar: 3 GB", "bar_gb", 3},
+		{"Baz: 4 pages", "baz_pages", 4},
+		{"Qux: 5 parsecs", "qux", "5 parsecs"},
+	} {
+		key, value, err := p.ParseLine(tc.line)
+		assert.NilError(t, err)
+		assert.Equal(t, key, tc.key)
+		assert.Equal(t, value, tc.value)
+	}
+}
+
+func TestNormalizeUnits(t *testing.T) {
+	r := &HostInfo{
+		Disks: map[string]map[string]any{
+			"/dev/sda1": {
+				"luks": map[string]any{
+					"metadata_area_bytes": 16384,
+					"keyslots": []map[string]any{{
+						"key_bits":        512,
+						"cipher_key_bits": 3, // not whole bytes
+					}},
+				},
+			},
+		},
+		CPUInfo: map[string]any{"cache_size_kb": 12288},
+		Memory: map[string]any{
+			"mem_total_kb":     16,
+			"foo_mb":           2,
+			"bar_gb":           float64(3),
+			"baz_pages":        4,
+			"huge_pages_total": 5,
+		},
+	}
+	r.NormalizeUnits()
+
+	assert.DeepEqual(t, r.Memory, map[string]any{
+		"mem_total_bytes":  16 << 10,
+		"foo_bytes":        2 << 20,
+		"bar_bytes":        3 << 30,
+		"baz_pages":        4,
+		"huge_pages_total": 5,
+	})
+	assert.DeepEqual(t, r.CPUInfo, map[string]any{
+		"cache_size_bytes": 12288 << 10,
+	})
+	assert.DeepEqual(t, r.Disks["/dev/sda1"]["luks"], map[string]any{
+		"metadata_area_bytes": 16384,
+		"keyslots": []map[string]any{{
+			"key_bytes":       64,
+			"cipher_key_bits": 3,
+		}},
+	})
+
+	// Normalizing twice changes nothing.
+	b1, err := json.Marshal(r)
+	assert.NilError(t, err)
+	r.NormalizeUnits()
+	b2, err := json.Marshal(r)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b2), string(b1))
+}
+
+func TestGather_normalizeUnits(t *testing.T) {
+	mock := invoker.NewMock(t)
+	mock.ExpectInvoke("/sbin/blkid", "-o", "export").
+		Returns(blkidExportOutput, nil)
+	mock.ExpectInvoke("cryptsetup", "luksDump", "/dev/nvme0n1p3").
+		Returns(luksdump, nil)
+	mock.ExpectInvoke("cat", "/proc/meminfo").Returns(testMemInfo, nil)
+
+	r, err := Gather(testctx(t), mock,
+		Only("DiskAttrs", "MemInfo"),
+		Concurrency(1),
+		NormalizeUnits(),
+	)
+	assert.NilError(t, err)
+	assert.NilError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, r.Memory["mem_total_bytes"], 40_743_392<<10)
+	assertNotHasKey(t, r.Memory, "mem_total_kb")
+
+	luks := r.Disks["/dev/nvme0n1p3"]["luks"].(map[string]any)
+	ks := luks["keyslots"].([]map[string]any)[0]
+	assert.Equal(t, ks["key_bytes"], 64)
+	assertNotHasKey(t, ks, "key_bits")
+
+	typed := r.Typed()
+	assert.Equal(t, typed.Memory.TotalBytes, uint64(40_743_392<<10))
+
+	i := slices.IndexFunc(typed.BlockDevices, func(bd BlockDevice) bool {
+		return bd.Device == "/dev/nvme0n1p3"
+	})
+	assert.Assert(t, i >= 0)
+	assert.Equal(t, typed.BlockDevices[i].LUKS.Keyslots[0].KeyBits, 512)
+}
@@ -0,0 +1,216 @@
+package hostinfo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"gbenson.net/go/invoker"
+	"gbenson.net/go/logger"
+)
+
+// An Event is sent by [Watch] when a host's information changes.
+type Event struct {
+	// Time is when the gathering that produced the event started.
+	Time time.Time
+
+	// Snapshot is true for the first event sent, whose HostInfo is
+	// complete.  Subsequent events' HostInfos contain only the
+	// sections that changed, and their Changes field lists what
+	// changed within them.
+	Snapshot bool
+	HostInfo *HostInfo
+	Changes  []Change
+
+	// Err is non-nil if gathering failed entirely, in which case
+	// the other fields are unset.
+	Err error
+}
+
+// MinWatchInterval is the shortest interval [Watch] gathers at.
+const MinWatchInterval = time.Second
+
+// Watch gathers the host accessed by invoker every interval, and
+// sends an [Event] to the returned channel when anything changes.
+// The first event is a snapshot of the entire host; subsequent
+// events contain only the changed sections.  Changes are detected
+// using [Diff], so volatile values such as free memory are ignored.
+// Intervals shorter than [MinWatchInterval] are treated as
+// MinWatchInterval.  Options are as for [Gather].
+//
+// If some gatherers fail, the sections they would have gathered are
+// assumed unchanged.  If every gatherer fails an event with a non-nil
+// Err is sent, and watching continues.  The channel is closed when
+// ctx is canceled.
+func Watch(
+	ctx context.Context,
+	invoker invoker.Invoker,
+	interval time.Duration,
+	opts ...Option,
+) <-chan Event {
+	// Checked here, as NewTicker panics in the new goroutine.
+	interval = max(interval, MinWatchInterval)
+
+	ch := make(chan Event)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		watch(ctx, invoker, ticker.C, opts, ch)
+	}()
+	return ch
+}
+
+// watch gathers the host immediately and on every tick, sending
+// events to ch until ctx is canceled.
+func watch(
+	ctx context.Context,
+	invoker invoker.Invoker,
+	ticks <-chan time.Time,
+	opts []Option,
+	ch chan<- Event,
+) {
+	defer close(ch)
+
+	var prev *HostInfo
+	for {
+		e, ok := nextEvent(ctx, invoker, opts, prev)
+		if e.snapshot != nil {
+			prev = e.snapshot
+		}
+		if ok {
+			select {
+			case ch <- e.Event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ticks:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// A watchEvent is an Event, plus the complete HostInfo it was
+// derived from.
+type watchEvent struct {
+	Event
+	snapshot *HostInfo
+}
+
+// nextEvent gathers the host, and returns the event to send, if any,
+// and whether there is one.  The previous gathering is prev, or nil
+// if no previous gathering succeeded.
+func nextEvent(
+	ctx context.Context,
+	invoker invoker.Invoker,
+	opts []Option,
+	prev *HostInfo,
+) (watchEvent, bool) {
+	start := time.Now()
+	r, err := Gather(ctx, invoker, opts...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return watchEvent{}, false // shutting down
+		}
+		return watchEvent{Event: Event{Time: start, Err: err}}, true
+	}
+
+	if prev == nil {
+		return watchEvent{
+			Event:    Event{Time: start, Snapshot: true, HostInfo: r},
+			snapshot: r,
+		}, true
+	}
+
+	if r.Partial != nil {
+		r.carryForward(prev)
+	}
+
+	changes, err := Diff(prev, r)
+	if err != nil {
+		return watchEvent{Event: Event{Time: start, Err: err}}, true
+	} else if len(changes) == 0 {
+		return watchEvent{snapshot: r}, false
+	}
+
+	changed := make(map[string]bool)
+	for _, c := range changes {
+		changed[c.Path[0]] = true
+	}
+	logger.Ctx(ctx).Debug().
+		Int("changes", len(changes)).
+		Msg("Host changed")
+
+	result := r.sections(changed)
+	result.Meta = r.Meta
+	result.Partial = r.Partial
+
+	return watchEvent{
+		Event: Event{
+			Time:     start,
+			HostInfo: result,
+			Changes:  changes,
+		},
+		snapshot: r,
+	}, true
+}
+
+// sections returns a HostInfo containing only those of r's sections
+// whose serialized names are in names.
+func (r *HostInfo) sections(names map[string]bool) *HostInfo {
+	result := &HostInfo{SchemaVersion: r.SchemaVersion}
+	src := reflect.ValueOf(r).Elem()
+	dst := reflect.ValueOf(result).Elem()
+	for i := range src.NumField() {
+		if names[jsonName(src.Type().Field(i))] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return result
+}
+
+// carryForward copies sections missing from r from prev.  Sections
+// that several gatherers populate may be only partly missing, so the
+// entries of maps, and of maps nested in maps, that are missing from
+// r are copied from prev too.
+func (r *HostInfo) carryForward(prev *HostInfo) {
+	src := reflect.ValueOf(prev).Elem()
+	dst := reflect.ValueOf(r).Elem()
+	for i := range src.NumField() {
+		switch jsonName(src.Type().Field(i)) {
+		case "", "-", "schema_version", "meta":
+			continue
+		}
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		} else if dst.Field(i).Kind() == reflect.Map {
+			fillMap(dst.Field(i), src.Field(i))
+		}
+	}
+}
+
+// fillMap copies the entries of src whose keys are missing from dst
+// into dst.  Where both have a key whose values are maps, fillMap
+// fills dst's value from src's.
+func fillMap(dst, src reflect.Value) {
+	iter := src.MapRange()
+	for iter.Next() {
+		k, v := iter.Key(), iter.Value()
+		dv := dst.MapIndex(k)
+		if !dv.IsValid() {
+			dst.SetMapIndex(k, v)
+		} else if dv.Kind() == reflect.Map && v.Kind() == reflect.Map {
+			fillMap(dv, v)
+		}
+	}
+}
+
+// jsonName returns the name f is serialized with.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
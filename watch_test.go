@@ -0,0 +1,219 @@
+package hostinfo
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func nextWatchEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		assert.Assert(t, ok, "channel closed")
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	panic("unreachable")
+}
+
+// sendTick makes the watcher reading ticks gather again.  Because
+// the watcher only reads ticks between gatherings, sendTick returns
+// once any previous gathering is complete and its event, if any,
+// has been received.  The gathering it starts may still be running.
+func sendTick(t *testing.T, ticks chan<- time.Time) {
+	t.Helper()
+	select {
+	case ticks <- time.Now():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watcher")
+	}
+}
+
+// awaitEvent sends ticks until the watcher sends an event, which it
+// returns.
+func awaitEvent(t *testing.T, ch <-chan Event, ticks chan<- time.Time) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e, ok := <-ch:
+			assert.Assert(t, ok, "channel closed")
+			return e
+		case ticks <- time.Now():
+		case <-timeout:
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
+
+// startWatch starts a watcher which gathers whenever the test sends
+// to the returned channel.
+func startWatch(
+	t *testing.T,
+	ctx context.Context,
+	ci *cannedInvoker,
+	opts ...Option,
+) (<-chan Event, chan<- time.Time) {
+	ch := make(chan Event)
+	ticks := make(chan time.Time)
+	go watch(ctx, ci, ticks, opts, ch)
+	t.Cleanup(func() {
+		for range ch {
+		}
+	})
+	return ch, ticks
+}
+
+func TestWatch(t *testing.T) {
+	ci := &cannedInvoker{outputs: map[string][]byte{
+		"cat /proc/meminfo":   testMemInfo,
+		"cat /etc/os-release": ubuntuOSRelease,
+	}}
+
+	ctx, cancel := context.WithCancel(testctx(t))
+	defer cancel()
+	ch, ticks := startWatch(t, ctx, ci, Only("MemInfo", "OSRelease"))
+
+	e := nextWatchEvent(t, ch)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.Snapshot)
+	assert.Equal(t, e.HostInfo.OS["id"], "ubuntu")
+	assert.Equal(t, len(e.HostInfo.Memory), 55)
+	assert.Equal(t, len(e.Changes), 0)
+
+	// Volatile changes are ignored, so the next event is the upgrade.
+	ci.set("cat /proc/meminfo", bytes.Replace(testMemInfo,
+		[]byte("MemFree:"), []byte("MemFree: 1 kB\nX:"), 1))
+	sendTick(t, ticks)
+	sendTick(t, ticks) // the volatile change sent no event
+	ci.set("cat /etc/os-release", bytes.Replace(ubuntuOSRelease,
+		[]byte(`VERSION_ID="22.04"`), []byte(`VERSION_ID="24.04"`), 1))
+
+	e = awaitEvent(t, ch, ticks)
+	assert.NilError(t, e.Err)
+	assert.Check(t, !e.Snapshot)
+	assert.Equal(t, e.HostInfo.OS["version_id"], "24.04")
+	assert.Equal(t, len(e.HostInfo.Memory), 0)
+	assert.Check(t, e.HostInfo.Meta != nil)
+	assert.DeepEqual(t, e.Changes, []Change{{
+		Kind: Changed,
+		Path: Path{"operating_system", "version_id"},
+		Old:  "22.04",
+		New:  "24.04",
+	}})
+}
+
+func TestWatch_failures(t *testing.T) {
+	ci := &cannedInvoker{}
+
+	ctx, cancel := context.WithCancel(testctx(t))
+	defer cancel()
+	ch, ticks := startWatch(t, ctx, ci, Only("MemInfo", "OSRelease"))
+
+	e := nextWatchEvent(t, ch)
+	assert.Check(t, e.Err != nil)
+	assert.Check(t, e.HostInfo == nil)
+
+	ci.set("cat /proc/meminfo", testMemInfo)
+	ci.set("cat /etc/os-release", fedoraOSRelease)
+
+	e = awaitEvent(t, ch, ticks)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.Snapshot)
+	assert.Equal(t, e.HostInfo.OS["id"], "fedora")
+
+	// A failing gatherer doesn't remove its section.
+	ci.set("cat /etc/os-release", nil)
+	sendTick(t, ticks)
+	sendTick(t, ticks) // the failure sent no event
+	ci.set("cat /proc/meminfo", bytes.Replace(testMemInfo,
+		[]byte("MemTotal:"), []byte("MemTotal: 1 kB\nX:"), 1))
+
+	e = awaitEvent(t, ch, ticks)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.HostInfo.Partial != nil)
+	assert.Equal(t, len(e.HostInfo.OS), 0)
+	for _, c := range e.Changes {
+		assert.Equal(t, c.Path[0], "memory")
+	}
+}
+
+// A failing gatherer doesn't remove its part of a section another
+// gatherer also populates.
+func TestWatch_partialSection(t *testing.T) {
+	ci := &cannedInvoker{}
+	ci.set("/sbin/blkid -o export", blkidExportOutput)
+	ci.set("cryptsetup luksDump /dev/nvme0n1p3", luksdump)
+	ci.set("lsblk --json --bytes -O", testLsblkOutput)
+
+	ctx, cancel := context.WithCancel(testctx(t))
+	defer cancel()
+	ch, ticks := startWatch(t, ctx, ci, Only("DiskAttrs", "DiskTopology"))
+
+	e := nextWatchEvent(t, ch)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.Snapshot)
+	assert.Equal(t, e.HostInfo.Disks["/dev/nvme0n1p3"]["type"], "crypto_LUKS")
+
+	ci.set("/sbin/blkid -o export", nil)
+	sendTick(t, ticks)
+	sendTick(t, ticks) // the failure sent no event
+	ci.set("lsblk --json --bytes -O", bytes.Replace(testLsblkOutput,
+		[]byte("MZVL2512HCJQ"), []byte("MZVL21T0HCLR"), 1))
+
+	e = awaitEvent(t, ch, ticks)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.HostInfo.Partial != nil)
+	assert.DeepEqual(t, e.Changes, []Change{{
+		Kind: Changed,
+		Path: Path{"block_devices", "/dev/nvme0n1", "model"},
+		Old:  "SAMSUNG MZVL2512HCJQ-00BL7",
+		New:  "SAMSUNG MZVL21T0HCLR-00BL7",
+	}})
+
+	// Recovery reports nothing new either.
+	ci.set("/sbin/blkid -o export", blkidExportOutput)
+	sendTick(t, ticks)
+	sendTick(t, ticks)
+	ci.set("lsblk --json --bytes -O", testLsblkOutput)
+
+	e = awaitEvent(t, ch, ticks)
+	assert.NilError(t, e.Err)
+	assert.Check(t, e.HostInfo.Partial == nil)
+	assert.Equal(t, len(e.Changes), 1)
+}
+
+func TestWatch_cancel(t *testing.T) {
+	// Gatherers abandoned on cancellation may outlive the test, so
+	// neither the context nor the invoker may refer to t.
+	ctx, cancel := context.WithCancel(context.Background())
+	ci := &cannedInvoker{}
+	ch := Watch(ctx, ci, time.Hour, Only("MemInfo"))
+	cancel()
+	for range ch {
+	}
+}
+
+// Intervals that time.NewTicker would reject must not crash Watch.
+func TestWatch_interval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ci := &cannedInvoker{outputs: map[string][]byte{
+			"cat /proc/meminfo": testMemInfo,
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := Watch(ctx, ci, interval, Only("MemInfo"))
+
+		e := nextWatchEvent(t, ch)
+		assert.NilError(t, e.Err)
+		assert.Check(t, e.Snapshot)
+
+		cancel()
+		for range ch {
+		}
+	}
+}